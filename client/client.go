@@ -0,0 +1,262 @@
+// Package client 是 oneinfer serve 管理接口（/api/v1）的 Go 客户端。
+//
+//	c := client.New("http://127.0.0.1:9090")
+//	instance, err := c.StartModel(ctx, client.StartRequest{Model: "/models/qwen.gguf", Port: 8080})
+//
+// 所有方法都接受 context，用于超时和取消。只读的请求在网络错误和 429、502、503、504 时按退避时间重试，
+// 其他请求（如启动、停止、删除）只在连接失败、请求未发出时重试。服务端返回的错误为 *Error。
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultURL 本机 serve 的地址
+	DefaultURL = "http://127.0.0.1:9090"
+	// APIPrefix 管理接口的路径前缀
+	APIPrefix = "/api/v1"
+
+	defaultRetries = 2
+	defaultBackoff = 250 * time.Millisecond
+)
+
+// Client 访问一个 serve 实例，可以被多个 goroutine 同时使用
+type Client struct {
+	baseURL   string
+	http      *http.Client
+	token     string
+	userAgent string
+	retries   int
+	backoff   time.Duration
+}
+
+// Option 修改 Client 的设置
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义的 http.Client，如设置了代理、TLS 或超时的客户端。
+// 流式接口（日志跟随、事件流）会一直占用连接，不要设置 http.Client.Timeout，改用 context 控制。
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithToken 在每个请求中带上 Authorization: Bearer <token>，用于设置了 ONEINFER_API_TOKEN 的 serve
+// 或 serve 前面有认证代理的部署
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithRetries 设置失败后的重试次数和第一次重试前的等待时间，之后每次翻倍。retries 为 0 时不重试。
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithUserAgent 设置 User-Agent 请求头
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// New 创建客户端，baseURL 为 serve 的地址（不含 /api/v1），为空时使用 DefaultURL
+func New(baseURL string, opts ...Option) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	c := &Client{
+		baseURL:   strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), APIPrefix),
+		http:      &http.Client{},
+		userAgent: "oneinfer-client",
+		retries:   defaultRetries,
+		backoff:   defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL 返回 serve 的地址
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Error 服务端返回的错误
+type Error struct {
+	StatusCode int    // HTTP 状态码
+	Code       string // 错误码，如 not_found、conflict
+	Message    string
+	RequestID  string // 对应 serve 日志中的 request_id
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("server returned status %d", e.StatusCode)
+}
+
+// IsNotFound 判断 err 是否为服务端返回的 404
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict 判断 err 是否为服务端返回的 409，如端口已被占用、名称已存在
+func IsConflict(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// errorBody /api/v1 的 JSON 错误
+type errorBody struct {
+	Error struct {
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		RequestID string `json:"request_id"`
+	} `json:"error"`
+}
+
+// responseError 根据响应生成 *Error，兼容纯文本的错误
+func responseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	e := &Error{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+	var body errorBody
+	if json.Unmarshal(data, &body) == nil && body.Error.Message != "" {
+		e.Code, e.Message = body.Error.Code, body.Error.Message
+		if body.Error.RequestID != "" {
+			e.RequestID = body.Error.RequestID
+		}
+	} else {
+		e.Message = strings.TrimSpace(string(data))
+	}
+	if e.Code == "" {
+		e.Code = strings.ReplaceAll(strings.ToLower(http.StatusText(resp.StatusCode)), " ", "_")
+	}
+	return e
+}
+
+// request 描述一次 API 调用
+type request struct {
+	method string
+	path   string     // /api/v1 之后的路径
+	query  url.Values // 可为空
+	body   interface{}
+	// expect 表示成功的状态码，为空时接受所有 2xx
+	expect []int
+}
+
+// do 发送请求，失败时按设置重试，成功时返回未读取的响应，由调用方关闭
+func (c *Client) do(ctx context.Context, r request) (*http.Response, error) {
+	var body []byte
+	if r.body != nil {
+		var err error
+		if body, err = json.Marshal(r.body); err != nil {
+			return nil, err
+		}
+	}
+	u := c.baseURL + APIPrefix + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+	// DELETE 虽然是幂等的，但第一次请求可能已经生效而响应丢失，重试会得到 404 或 409，
+	// 把成功报告为失败，因此和 POST、PATCH 一样只在请求未发出时重试
+	idempotent := r.method == http.MethodGet || r.method == http.MethodHead
+
+	delay := c.backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, r.method, u, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("User-Agent", c.userAgent)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := c.http.Do(req)
+		retry := false
+		switch {
+		case err != nil:
+			retry = idempotent || isDialError(err)
+		case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusBadGateway,
+			resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+			retry = idempotent
+		}
+		if !retry || attempt >= c.retries || ctx.Err() != nil {
+			if err != nil {
+				return nil, err
+			}
+			if !expected(resp.StatusCode, r.expect) {
+				defer resp.Body.Close()
+				return nil, responseError(resp)
+			}
+			return resp, nil
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// doJSON 发送请求，并把成功的响应解析到 out（可为 nil）
+func (c *Client) doJSON(ctx context.Context, r request, out interface{}) error {
+	resp, err := c.do(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return decode(resp, out)
+}
+
+// decode 解析 JSON 响应
+func decode(resp *http.Response, out interface{}) error {
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %v", err)
+	}
+	return nil
+}
+
+// expected 判断状态码是否表示成功
+func expected(status int, expect []int) bool {
+	if len(expect) == 0 {
+		return status >= 200 && status < 300
+	}
+	for _, s := range expect {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// isDialError 判断错误是否发生在建立连接时，此时请求没有发出，重试是安全的
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,351 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient 启动 handler 并返回连接它的客户端，重试间隔缩短到 1ms
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return New(srv.URL, append([]Option{WithRetries(2, time.Millisecond)}, opts...)...)
+}
+
+func TestErrorDecoding(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case APIPrefix + "/models/missing":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Request-ID", "from-header")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error": {"code": "not_found", "message": "model 'missing' not found", "request_id": "abc123"}}`)
+		case APIPrefix + "/instances":
+			w.Header().Set("X-Request-ID", "req-9")
+			http.Error(w, "Port 8080 is already in use", http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	ctx := context.Background()
+
+	_, err := c.GetModel(ctx, "missing")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error %v is not *Error", err)
+	}
+	want := Error{StatusCode: 404, Code: "not_found", Message: "model 'missing' not found", RequestID: "abc123"}
+	if *apiErr != want {
+		t.Errorf("JSON error = %+v, want %+v", *apiErr, want)
+	}
+	if !IsNotFound(err) || IsConflict(err) || err.Error() != want.Message {
+		t.Errorf("IsNotFound/IsConflict/Error() wrong for %v", err)
+	}
+
+	// 纯文本错误：错误码由状态码生成，请求 ID 取自响应头
+	_, err = c.StartModel(ctx, StartRequest{Model: "m.gguf", Port: 8080})
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error %v is not *Error", err)
+	}
+	want = Error{StatusCode: 409, Code: "conflict", Message: "Port 8080 is already in use", RequestID: "req-9"}
+	if *apiErr != want || !IsConflict(err) {
+		t.Errorf("text error = %+v, want %+v", *apiErr, want)
+	}
+
+	// 没有响应体时使用状态码
+	_, err = c.Daemon(ctx)
+	if err == nil || err.Error() != "server returned status 500" {
+		t.Errorf("empty error = %v", err)
+	}
+}
+
+// statusSequence 依次返回 statuses 中的状态码，用完后返回 200，并统计请求数
+func statusSequence(attempts *atomic.Int32, statuses ...int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := int(attempts.Add(1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"status": "ok"}`)
+	}
+}
+
+func TestRetryReadOnlyRequests(t *testing.T) {
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	c := newTestClient(t, statusSequence(&attempts, 503, 502))
+	if _, err := c.Daemon(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("GET after 503, 502: %d attempts, want 3", attempts.Load())
+	}
+
+	attempts.Store(0)
+	c = newTestClient(t, statusSequence(&attempts, 503, 503, 503, 503))
+	if _, err := c.Daemon(ctx); !errors.As(err, new(*Error)) {
+		t.Errorf("error = %v, want the last 503", err)
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("GET with retries exhausted: %d attempts, want 3", attempts.Load())
+	}
+
+	// 其他错误状态不重试
+	attempts.Store(0)
+	c = newTestClient(t, statusSequence(&attempts, 500))
+	c.Daemon(ctx)
+	if attempts.Load() != 1 {
+		t.Errorf("GET after 500: %d attempts, want 1", attempts.Load())
+	}
+
+	attempts.Store(0)
+	c = newTestClient(t, statusSequence(&attempts, 503), WithRetries(0, time.Millisecond))
+	c.Daemon(ctx)
+	if attempts.Load() != 1 {
+		t.Errorf("WithRetries(0): %d attempts, want 1", attempts.Load())
+	}
+}
+
+// 修改状态的请求，包括 DELETE，收到响应后不重试
+func TestNoRetryForChanges(t *testing.T) {
+	ctx := context.Background()
+	calls := map[string]func(c *Client) error{
+		"POST": func(c *Client) error {
+			_, err := c.StartModel(ctx, StartRequest{Model: "m.gguf", Port: 8080})
+			return err
+		},
+		"PATCH": func(c *Client) error {
+			_, err := c.UpdateModel(ctx, "m", ModelUpdate{})
+			return err
+		},
+		"DELETE": func(c *Client) error { return c.RemoveModel(ctx, "m") },
+	}
+	for method, call := range calls {
+		var attempts atomic.Int32
+		c := newTestClient(t, statusSequence(&attempts, 503, 503))
+		if err := call(c); err == nil {
+			t.Errorf("%s: expected the 503 error", method)
+		}
+		if attempts.Load() != 1 {
+			t.Errorf("%s after 503: %d attempts, want 1", method, attempts.Load())
+		}
+	}
+}
+
+// 连接被断开时请求可能已经发出，只有 GET 重试
+func TestRetryAfterDroppedConnection(t *testing.T) {
+	ctx := context.Background()
+	var attempts atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		io.WriteString(w, `{}`)
+	})
+
+	if _, err := c.Daemon(ctx); err != nil {
+		t.Errorf("GET: %v", err)
+	}
+	if attempts.Load() != 2 {
+		t.Errorf("GET: %d attempts, want 2", attempts.Load())
+	}
+
+	attempts.Store(0)
+	if _, err := c.StopInstance(ctx, 1); err == nil {
+		t.Error("DELETE: expected the connection error")
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("DELETE: %d attempts, want 1", attempts.Load())
+	}
+}
+
+// 无法建立连接时请求没有发出，任何方法都可以重试
+func TestRetryDialError(t *testing.T) {
+	var dials atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id": 1}`)
+	}))
+	defer srv.Close()
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if dials.Add(1) == 1 {
+				return nil, &net.OpError{Op: "dial", Net: network, Err: errors.New("connection refused")}
+			}
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	c := New(srv.URL, WithHTTPClient(&http.Client{Transport: transport}), WithRetries(2, time.Millisecond))
+	instance, err := c.StartModel(context.Background(), StartRequest{Model: "m.gguf", Port: 8080})
+	if err != nil || instance.ID != 1 {
+		t.Fatalf("StartModel = %+v, %v", instance, err)
+	}
+	if dials.Load() != 2 {
+		t.Errorf("%d dials, want 2", dials.Load())
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	var auth, agent string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		auth, agent = r.Header.Get("Authorization"), r.Header.Get("User-Agent")
+		io.WriteString(w, `{}`)
+	}
+	ctx := context.Background()
+
+	c := newTestClient(t, handler, WithToken("s3cret"), WithUserAgent("test-agent"))
+	c.Daemon(ctx)
+	if auth != "Bearer s3cret" || agent != "test-agent" {
+		t.Errorf("Authorization = %q, User-Agent = %q", auth, agent)
+	}
+	c.RemoveModel(ctx, "m")
+	if auth != "Bearer s3cret" {
+		t.Errorf("DELETE: Authorization = %q", auth)
+	}
+
+	c = newTestClient(t, handler)
+	c.Daemon(ctx)
+	if auth != "" || agent != "oneinfer-client" {
+		t.Errorf("without a token: Authorization = %q, User-Agent = %q", auth, agent)
+	}
+}
+
+func TestNewBaseURL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", DefaultURL},
+		{"http://gpu-box:9090/", "http://gpu-box:9090"},
+		{"http://gpu-box:9090/api/v1", "http://gpu-box:9090"},
+	}
+	for _, tt := range tests {
+		if got := New(tt.in).BaseURL(); got != tt.want {
+			t.Errorf("New(%q).BaseURL() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStreamLogs(t *testing.T) {
+	release := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != APIPrefix+"/instances/7/logs" || r.URL.Query().Get("follow") != "true" || r.URL.Query().Get("tail") != "2" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		for i := 1; i <= 2; i++ {
+			fmt.Fprintf(w, "line %d\n", i)
+			w.(http.Flusher).Flush()
+		}
+		// 跟随时保持连接，直到客户端断开
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logs, err := c.StreamLogs(ctx, 7, LogOptions{Follow: true, Tail: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logs.Close()
+	reader := bufio.NewReader(logs)
+	for i := 1; i <= 2; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil || line != fmt.Sprintf("line %d\n", i) {
+			t.Fatalf("line %d = %q, %v", i, line, err)
+		}
+	}
+
+	// 取消 context 后读取结束
+	done := make(chan error, 1)
+	go func() {
+		_, err := reader.ReadString('\n')
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("read succeeded after cancel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("log stream did not end after cancel")
+	}
+}
+
+func TestStreamLogsNotFound(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error": {"code": "not_found", "message": "Process not found"}}`)
+	})
+	if _, err := c.StreamLogs(context.Background(), 1, LogOptions{}); !IsNotFound(err) {
+		t.Errorf("error = %v, want not found", err)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("type") != "model,download" || r.URL.Query().Get("last_event_id") != "4" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, ": keep-alive\n\n")
+		io.WriteString(w, "id: 5\nevent: model.started\ndata: {\"id\":5,\"type\":\"model.started\",\"data\":{\"id\":1}}\n\n")
+		io.WriteString(w, "data: not json\n\n")
+		io.WriteString(w, "id: 6\nevent: download.queued\ndata: {\"id\":6,\"type\":\"download.queued\",\"data\":{}}\n\n")
+	})
+	stream, err := c.StreamEvents(context.Background(), EventOptions{Types: []string{"model", "download"}, LastEventID: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	for _, want := range []string{"model.started", "download.queued"} {
+		ev, err := stream.Next()
+		if err != nil || ev.Type != want {
+			t.Fatalf("Next = %+v, %v, want %s", ev, err, want)
+		}
+	}
+	if stream.LastEventID() != 6 {
+		t.Errorf("LastEventID = %d, want 6", stream.LastEventID())
+	}
+	if _, err := stream.Next(); err != io.EOF {
+		t.Errorf("end of stream: %v, want io.EOF", err)
+	}
+}
+
+func TestAddModelAccepted(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || !strings.Contains(string(body), `"platform":"local"`) {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, `{"id": "d1", "platform": "local", "repo": "/data/q", "name": "q", "mode": "copy", "status": "queued"}`)
+	})
+	result, err := c.AddModel(context.Background(), AddModelRequest{Platform: "local", Name: "q", Path: "/data/q"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Model != nil || result.Download == nil || result.Download.ID != "d1" || result.Download.Name != "q" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
@@ -0,0 +1,24 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Daemon 返回 serve 的运行状态
+func (c *Client) Daemon(ctx context.Context) (*Daemon, error) {
+	var daemon Daemon
+	if err := c.doJSON(ctx, request{method: http.MethodGet, path: "/daemon"}, &daemon); err != nil {
+		return nil, err
+	}
+	return &daemon, nil
+}
+
+// Shutdown 停止所有模型并退出 serve
+func (c *Client) Shutdown(ctx context.Context) error {
+	return c.doJSON(ctx, request{
+		method: http.MethodPost,
+		path:   "/daemon/shutdown",
+		expect: []int{http.StatusAccepted},
+	}, nil)
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListDownloads 列出排队、进行中和最近结束的下载，按创建时间排序
+func (c *Client) ListDownloads(ctx context.Context) ([]Download, error) {
+	var downloads []Download
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: "/downloads"}, &downloads)
+	return downloads, err
+}
+
+// CreateDownload 创建后台下载任务，任务排队等待 serve 的下载名额，完成后注册模型。
+// 同一个下载已在进行时返回 409。
+func (c *Client) CreateDownload(ctx context.Context, req DownloadRequest) (*Download, error) {
+	var download Download
+	err := c.doJSON(ctx, request{
+		method: http.MethodPost,
+		path:   "/downloads",
+		body:   req,
+		expect: []int{http.StatusAccepted},
+	}, &download)
+	if err != nil {
+		return nil, err
+	}
+	return &download, nil
+}
+
+// GetDownload 返回一个下载的进度
+func (c *Client) GetDownload(ctx context.Context, id string) (*Download, error) {
+	var download Download
+	if err := c.doJSON(ctx, request{method: http.MethodGet, path: "/downloads/" + url.PathEscape(id)}, &download); err != nil {
+		return nil, err
+	}
+	return &download, nil
+}
+
+// CancelDownload 取消排队或下载中的任务，任务结束时发布 download.canceled 事件。
+// 已结束的下载和前台下载返回 409。
+func (c *Client) CancelDownload(ctx context.Context, id string) (*Download, error) {
+	var download Download
+	err := c.doJSON(ctx, request{
+		method: http.MethodDelete,
+		path:   "/downloads/" + url.PathEscape(id),
+		expect: []int{http.StatusAccepted},
+	}, &download)
+	if err != nil {
+		return nil, err
+	}
+	return &download, nil
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PublishEvent 向 serve 发布一个事件，目前只接受 download.* 事件
+func (c *Client) PublishEvent(ctx context.Context, eventType string, data interface{}) error {
+	body := map[string]interface{}{"type": eventType, "data": data}
+	return c.doJSON(ctx, request{method: http.MethodPost, path: "/events", body: body}, nil)
+}
+
+// EventStream 一个打开的事件流
+type EventStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	lastID  uint64
+}
+
+// StreamEvents 订阅 serve 的事件，ctx 取消或调用 Close 后结束
+func (c *Client) StreamEvents(ctx context.Context, opts EventOptions) (*EventStream, error) {
+	query := url.Values{}
+	if len(opts.Types) > 0 {
+		query.Set("type", strings.Join(opts.Types, ","))
+	}
+	if opts.LastEventID > 0 {
+		query.Set("last_event_id", strconv.FormatUint(opts.LastEventID, 10))
+	}
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: "/events", query: query})
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	return &EventStream{body: resp.Body, scanner: scanner, lastID: opts.LastEventID}, nil
+}
+
+// Next 阻塞到下一个事件。服务端关闭事件流时返回 io.EOF，
+// 之后可以用 LastEventID 重新订阅，补发错过的事件。
+func (s *EventStream) Next() (*Event, error) {
+	// 只需要 data 行，id 和 event 已包含在 JSON 中
+	for s.scanner.Scan() {
+		data, ok := strings.CutPrefix(s.scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			continue
+		}
+		s.lastID = ev.ID
+		return &ev, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// LastEventID 返回最近收到的事件 ID
+func (s *EventStream) LastEventID() uint64 {
+	return s.lastID
+}
+
+// Close 关闭事件流
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
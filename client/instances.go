@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListInstances 列出 serve 管理的模型实例，按 ID 排序
+func (c *Client) ListInstances(ctx context.Context) ([]Instance, error) {
+	var instances []Instance
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: "/instances"}, &instances)
+	return instances, err
+}
+
+// GetInstance 返回一个模型实例
+func (c *Client) GetInstance(ctx context.Context, id int) (*Instance, error) {
+	var instance Instance
+	if err := c.doJSON(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/instances/%d", id)}, &instance); err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// StartModel 启动模型，端口已被占用时返回 409
+func (c *Client) StartModel(ctx context.Context, req StartRequest) (*Instance, error) {
+	var instance Instance
+	err := c.doJSON(ctx, request{
+		method: http.MethodPost,
+		path:   "/instances",
+		body:   req,
+		expect: []int{http.StatusCreated},
+	}, &instance)
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// StopInstance 停止模型实例，返回停止前的状态
+func (c *Client) StopInstance(ctx context.Context, id int) (*Instance, error) {
+	var instance Instance
+	if err := c.doJSON(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/instances/%d", id)}, &instance); err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// StreamLogs 读取模型实例的输出，调用方负责关闭返回的 ReadCloser。
+// Follow 为 true 时持续输出，直到实例停止或 ctx 取消。
+func (c *Client) StreamLogs(ctx context.Context, id int, opts LogOptions) (io.ReadCloser, error) {
+	query := url.Values{}
+	if opts.Follow {
+		query.Set("follow", "true")
+	}
+	if opts.Tail > 0 {
+		query.Set("tail", strconv.Itoa(opts.Tail))
+	}
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: fmt.Sprintf("/instances/%d/logs", id), query: query})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
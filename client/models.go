@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// modelPath 返回模型的接口路径，名称中的 "/" 保留为路径分隔符
+func modelPath(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return "/models/" + strings.Join(parts, "/")
+}
+
+// ListRegistry 列出注册表中的模型
+func (c *Client) ListRegistry(ctx context.Context) ([]Model, error) {
+	var models []Model
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: "/models"}, &models)
+	return models, err
+}
+
+// GetModel 按名称或别名查找模型
+func (c *Client) GetModel(ctx context.Context, name string) (*Model, error) {
+	var model Model
+	if err := c.doJSON(ctx, request{method: http.MethodGet, path: modelPath(name)}, &model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// AddModel 添加模型，见 AddModelResult
+func (c *Client) AddModel(ctx context.Context, req AddModelRequest) (*AddModelResult, error) {
+	resp, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/models",
+		body:   req,
+		expect: []int{http.StatusCreated, http.StatusAccepted},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusAccepted {
+		var download Download
+		if err := decode(resp, &download); err != nil {
+			return nil, err
+		}
+		return &AddModelResult{Download: &download}, nil
+	}
+	var model Model
+	if err := decode(resp, &model); err != nil {
+		return nil, err
+	}
+	return &AddModelResult{Model: &model}, nil
+}
+
+// UpdateModel 修改模型的别名、标签或默认运行参数
+func (c *Client) UpdateModel(ctx context.Context, name string, update ModelUpdate) (*Model, error) {
+	var model Model
+	if err := c.doJSON(ctx, request{method: http.MethodPatch, path: modelPath(name), body: update}, &model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// RemoveModel 删除模型及其文件，模型有实例在运行时返回 409
+func (c *Client) RemoveModel(ctx context.Context, name string) error {
+	return c.doJSON(ctx, request{method: http.MethodDelete, path: modelPath(name)}, nil)
+}
+
+// ModelGGUF 读取模型 GGUF 文件头中的元数据
+func (c *Client) ModelGGUF(ctx context.Context, name string) (*GGUFInfo, error) {
+	var info GGUFInfo
+	if err := c.doJSON(ctx, request{method: http.MethodGet, path: modelPath(name) + "/gguf"}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
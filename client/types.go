@@ -0,0 +1,216 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Model 注册表中的一个模型
+type Model struct {
+	Name        string       `json:"name"`
+	Platform    string       `json:"platform"`
+	Source      string       `json:"source,omitempty"`       // 远程仓库 ID 或本地原始路径
+	FilePattern string       `json:"file_pattern,omitempty"` // 下载时使用的文件模式
+	Revision    string       `json:"revision,omitempty"`     // 远程模型的分支、标签或提交
+	Commit      string       `json:"commit,omitempty"`       // 下载时 revision 对应的提交 SHA
+	Path        string       `json:"path"`                   // 传给推理后端的模型路径
+	Mode        string       `json:"mode,omitempty"`         // 本地模型的导入方式
+	Mmproj      string       `json:"mmproj,omitempty"`       // 多模态模型的 mmproj 文件
+	Aliases     []string     `json:"aliases,omitempty"`
+	Labels      []string     `json:"labels,omitempty"`
+	Files       []ModelFile  `json:"files,omitempty"`
+	Defaults    *RunDefaults `json:"defaults,omitempty"` // run 命令的默认参数
+	Size        int64        `json:"size"`
+	AddedDate   string       `json:"added_date,omitempty"`
+}
+
+// ModelFile 模型的一个文件
+type ModelFile struct {
+	Path     string `json:"path"`               // 相对于模型目录的路径
+	Digest   string `json:"digest"`             // sha256:<hex>
+	Size     int64  `json:"size"`               // 字节数
+	External string `json:"external,omitempty"` // symlink/inplace 导入时文件的原始位置
+}
+
+// RunDefaults 模型的默认运行参数
+type RunDefaults struct {
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+}
+
+// AddModelRequest 添加模型的参数。远程模型填写 Repo，本地模型填写 Name 和 Path。
+type AddModelRequest struct {
+	Platform    string `json:"platform"`               // huggingface、modelscope 或 local
+	Repo        string `json:"repo,omitempty"`         // owner/name，可带 @revision
+	FilePattern string `json:"file_pattern,omitempty"` // 只下载匹配的文件
+	Quant       string `json:"quant,omitempty"`        // 按量化类型选择 GGUF 文件，如 Q4_K_M
+	FitMemory   bool   `json:"fit_memory,omitempty"`   // 选择能放入可用内存的最大 GGUF 文件
+	Name        string `json:"name,omitempty"`         // 本地模型的名称
+	Path        string `json:"path,omitempty"`         // serve 所在机器上的文件或目录，需位于 serve 的 import_roots 中
+	Mode        string `json:"mode,omitempty"`         // 本地模型的导入方式，默认 copy
+}
+
+// AddModelResult AddModel 的结果。serve 创建后台任务下载远程模型或导入本地模型，Download 为该任务，
+// 可以用 GetDownload 或 download.* 事件跟踪。旧版 serve 同步导入本地模型，此时 Model 为新模型。
+type AddModelResult struct {
+	Model    *Model
+	Download *Download
+}
+
+// ModelUpdate 修改模型的字段，为 nil 的字段保持不变
+type ModelUpdate struct {
+	Aliases       *[]string
+	Labels        *[]string
+	Defaults      *RunDefaults
+	ClearDefaults bool // 清除默认运行参数，优先于 Defaults
+}
+
+// MarshalJSON 只输出要修改的字段
+func (u ModelUpdate) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{}
+	if u.Aliases != nil {
+		m["aliases"] = nonNil(*u.Aliases)
+	}
+	if u.Labels != nil {
+		m["labels"] = nonNil(*u.Labels)
+	}
+	if u.ClearDefaults {
+		m["defaults"] = nil
+	} else if u.Defaults != nil {
+		m["defaults"] = u.Defaults
+	}
+	return json.Marshal(m)
+}
+
+// nonNil 让空列表编码为 []，表示清空
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// GGUFInfo 模型 GGUF 文件头中的信息
+type GGUFInfo struct {
+	Path        string         `json:"path"`
+	Version     uint32         `json:"version"`
+	TensorCount int            `json:"tensor_count"`
+	FileSize    int64          `json:"file_size"`
+	Metadata    []GGUFMetadata `json:"metadata"`
+}
+
+// GGUFMetadata 一个元数据键值，数组只包含类型和长度
+type GGUFMetadata struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// 实例状态
+const (
+	StatusStarting   = "starting"
+	StatusRunning    = "running"
+	StatusRestarting = "restarting"
+	StatusFailed     = "failed"
+	StatusStopped    = "stopped"
+	StatusRefused    = "refused"
+)
+
+// Instance 一个运行中的模型进程及其资源占用
+type Instance struct {
+	ID            int     `json:"id"` // serve 分配的实例 ID，自动重启后不变
+	Model         string  `json:"model"`
+	Name          string  `json:"name,omitempty"` // 注册表中的模型名称
+	Status        string  `json:"status"`
+	Host          string  `json:"host"`
+	Port          int     `json:"port"`
+	Restarts      int     `json:"restarts"`
+	UptimeSeconds int64   `json:"uptime_seconds"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	RSSBytes      int64   `json:"rss_bytes"`
+	Threads       int     `json:"threads"`
+	Connections   int     `json:"connections"`
+}
+
+// StartRequest 启动模型的参数
+type StartRequest struct {
+	Model  string `json:"model"`            // serve 所在机器上的模型文件路径
+	Mmproj string `json:"mmproj,omitempty"` // 多模态模型的 mmproj 文件
+	Name   string `json:"name,omitempty"`   // 注册表中的模型名称，用于显示和统计
+	Host   string `json:"host"`
+	Port   int    `json:"port"`
+}
+
+// LogOptions StreamLogs 的参数
+type LogOptions struct {
+	Tail   int  // 只返回最后 Tail 行，0 表示全部
+	Follow bool // 持续输出新的日志，直到 context 取消
+}
+
+// 下载状态
+const (
+	DownloadQueued    = "queued"
+	DownloadRunning   = "downloading"
+	DownloadCompleted = "completed"
+	DownloadFailed    = "failed"
+	DownloadCanceled  = "canceled"
+)
+
+// DownloadRequest 创建后台下载任务的参数，FilePattern 与 Quant、FitMemory 只能指定一种
+type DownloadRequest struct {
+	Platform    string `json:"platform"`               // huggingface 或 modelscope
+	Repo        string `json:"repo"`                   // owner/name，可带 @revision
+	FilePattern string `json:"file_pattern,omitempty"` // 只下载匹配的文件
+	Quant       string `json:"quant,omitempty"`        // 按量化类型选择 GGUF 文件，如 Q4_K_M
+	FitMemory   bool   `json:"fit_memory,omitempty"`   // 选择能放入可用内存的最大 GGUF 文件
+}
+
+// Download 一个下载：serve 的后台下载或本地导入任务，或 oneinfer add 在前台执行时上报的下载。
+// download.* 事件的数据也是 Download。
+type Download struct {
+	ID         string     `json:"id"`
+	Platform   string     `json:"platform"`
+	Repo       string     `json:"repo"` // 本地导入任务为服务器上的路径
+	Revision   string     `json:"revision,omitempty"`
+	Files      string     `json:"files,omitempty"` // 文件模式
+	Quant      string     `json:"quant,omitempty"`
+	FitMemory  bool       `json:"fit_memory,omitempty"`
+	Name       string     `json:"name,omitempty"` // 本地模型的名称
+	Mode       string     `json:"mode,omitempty"` // 本地模型的导入方式
+	Bytes      int64      `json:"bytes"`          // 已下载的字节数
+	Status     string     `json:"status"`
+	Model      string     `json:"model,omitempty"` // 完成后注册的模型名称
+	Error      string     `json:"error,omitempty"`
+	Foreground bool       `json:"foreground,omitempty"` // 由 oneinfer add 在前台执行，不能通过 API 取消
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Finished 判断下载是否已结束
+func (d Download) Finished() bool {
+	return d.Status == DownloadCompleted || d.Status == DownloadFailed || d.Status == DownloadCanceled
+}
+
+// Event serve 推送的一个事件，Data 的结构取决于 Type
+type Event struct {
+	ID   uint64          `json:"id"`
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// EventOptions StreamEvents 的参数
+type EventOptions struct {
+	Types       []string // 事件类型或分组，如 model.ready、download，为空时接收全部
+	LastEventID uint64   // 补发该 ID 之后的事件
+}
+
+// Daemon serve 的运行状态
+type Daemon struct {
+	Status        string    `json:"status"`
+	APIVersion    string    `json:"api_version"`
+	PID           int       `json:"pid"`
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	Instances     int       `json:"instances"`
+}
@@ -1,22 +1,36 @@
 package cmd
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
 
+	"oneinfer/client"
+
 	"github.com/spf13/cobra"
 )
 
 var modelAddCmd = &cobra.Command{
-	Use:   "add <model_name> <platform_name_or_local> [file_pattern]",
+	Use:   "add <repo_id[@revision]|model_name> <platform_name_or_local> [file_pattern_or_local_path]",
 	Short: "Add a model by specifying either a local path or platform and model name, with an optional file pattern to limit the download",
-	Args:  cobra.MinimumNArgs(2), // 至少两个参数，平台和模型名，第3个是可选的文件模式
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Add a model from Hugging Face, ModelScope or the local filesystem.
+
+For remote platforms the optional third argument is a file pattern that limits the download,
+and the repository may be suffixed with @<branch|tag|commit> to pin a revision.
+For "local" the third argument (or --path) is a model file or a directory, e.g. a sharded GGUF
+set, a GGUF with its mmproj file or a safetensors folder. --mode controls how local files are
+imported: copy (default), hardlink, symlink or inplace (register the original path as is).
+
+With --async a remote model is downloaded by oneinfer serve in the background instead, so the
+download survives closing the terminal and is resumed when serve restarts. Follow it with
+'oneinfer downloads <download_id> --follow'.`,
+	Args: validArgs(cobra.RangeArgs(2, 3)), // 至少两个参数，平台和模型名，第3个是可选的文件模式或本地路径
+	// 补全平台名
+	ValidArgsFunction: completeAddArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		modelName := args[0]
 		platformOrPath := args[1]
 		var filePattern string
@@ -26,225 +40,217 @@ var modelAddCmd = &cobra.Command{
 			filePattern = args[2]
 		}
 
-		err := addModel(modelName, platformOrPath, filePattern)
+		installDeps, _ := cmd.Flags().GetBool("install-deps")
+		if os.Getenv("ONEINFER_INSTALL_DEPS") == "1" {
+			installDeps = true
+		}
+		opts := addOptions{InstallDeps: installDeps}
+		opts.LocalPath, _ = cmd.Flags().GetString("path")
+		opts.Mode, _ = cmd.Flags().GetString("mode")
+		opts.Quant, _ = cmd.Flags().GetString("quant")
+		opts.FitMemory, _ = cmd.Flags().GetBool("fit-memory")
+
+		// 本地模型的路径可以通过第三个参数或 --path 指定
+		if platformOrPath == "local" && filePattern != "" {
+			if opts.LocalPath != "" {
+				return newError(errValidation, "specify the local path either as an argument or with --path, not both")
+			}
+			opts.LocalPath = filePattern
+			filePattern = ""
+		}
+
+		if async, _ := cmd.Flags().GetBool("async"); async {
+			return addModelAsync(modelName, platformOrPath, filePattern, opts)
+		}
+
+		model, err := addModel(context.Background(), modelName, platformOrPath, filePattern, opts)
 		if err != nil {
-			fmt.Println("Error:", err)
-			return
+			return err
 		}
-		fmt.Printf("Model '%s' added successfully!\n", modelName)
+		return printResult(model, func(w io.Writer) {
+			fmt.Fprintf(w, "Model '%s' added successfully!\n", model.Name)
+		})
 	},
 }
 
+// addOptions add 命令的可选参数
+type addOptions struct {
+	InstallDeps bool   // 缺少 Python 依赖时自动安装
+	LocalPath   string // 本地模型文件或目录
+	Mode        string // 本地模型的导入方式
+	Quant       string // 按量化类型从 GGUF 仓库中选择文件
+	FitMemory   bool   // 选择能放入可用内存的最大 GGUF 文件
+
+	// serve 的下载任务使用以下设置，CLI 前台下载时为空
+	StagingDir string                                     // 固定的下载暂存目录，重启后在其中续传，由调用方删除
+	Progress   func(eventType string, data downloadEvent) // 接收下载事件，为空时上报给 serve
+	Output     io.Writer                                  // 接收下载脚本的输出，为空时输出到终端
+	Logger     *slog.Logger                               // 接收提示和警告，为空时输出到终端
+}
+
 func init() {
+	modelAddCmd.Flags().Bool("install-deps", false, "Install missing Python download libraries with pip (or set ONEINFER_INSTALL_DEPS=1)")
+	modelAddCmd.Flags().String("path", "", "Local model file or directory (platform \"local\" only)")
+	modelAddCmd.Flags().String("mode", importCopy, "How to import local files: copy, hardlink, symlink or inplace")
+	modelAddCmd.Flags().String("quant", "", "Pick the GGUF file with this quantization from the repository, e.g. Q4_K_M")
+	modelAddCmd.Flags().Bool("fit-memory", false, "Pick the largest GGUF file in the repository that fits in the available memory")
+	modelAddCmd.Flags().Bool("async", false, "Let oneinfer serve download the model in the background and return immediately")
+	modelAddCmd.RegisterFlagCompletionFunc("quant", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"Q2_K", "Q3_K_M", "Q4_K_M", "Q5_K_M", "Q6_K", "Q8_0", "BF16", "F16"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	modelAddCmd.RegisterFlagCompletionFunc("mode", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return importModes, cobra.ShellCompDirectiveNoFileComp
+	})
 	rootCmd.AddCommand(modelAddCmd)
 }
 
-// addModel 根据平台名或本地路径添加模型
-func addModel(name, platformOrPath, filePattern string) error {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return err
-	}
-
-	modelDir := filepath.Join(homeDir, ".oneinfer", "models")
-	if err := os.MkdirAll(modelDir, 0755); err != nil {
-		return err
-	}
-
-	var destPath string
-	var errCopy error
-
-	// 如果平台是 "local"，则提示用户输入本地文件路径
+// addModel 根据平台名或本地路径添加模型，ctx 取消时中断下载
+func addModel(ctx context.Context, name, platformOrPath, filePattern string, opts addOptions) (modelInfo, error) {
+	// 本地模型：按导入方式复制、链接或原地注册
 	if platformOrPath == "local" {
-		var localPath string
-		fmt.Print("Enter the local file path for the model: ")
-		_, err := fmt.Scanln(&localPath)
-		if err != nil {
-			return fmt.Errorf("invalid input: %v", err)
+		if opts.Quant != "" || opts.FitMemory {
+			return modelInfo{}, newError(errValidation, "--quant and --fit-memory are only supported for Hugging Face and ModelScope")
 		}
+		return importLocalModel(name, opts.LocalPath, opts.Mode, opts.Logger)
+	}
 
-		// 检查本地路径是否有效
-		if fileInfo, err := os.Stat(localPath); err == nil && !fileInfo.IsDir() {
-			// 创建一个以模型名称为名的文件夹
-			modelFolder := filepath.Join(modelDir, name)
-			if err := os.MkdirAll(modelFolder, 0755); err != nil {
-				return fmt.Errorf("failed to create model folder: %v", err)
-			}
-
-			// 设置目标路径为该文件夹中的模型文件
-			destfilePath := filepath.Join(modelFolder, name+filepath.Ext(localPath))
-			errCopy = copyFile(localPath, destfilePath)
-
-			// 后续模型保存元数据
-			name = name + filepath.Ext(localPath)
-			destPath = modelFolder
-		} else {
-			return fmt.Errorf("invalid local model file path")
+	// 如果是远程平台，先校验参数，再调用 Python 下载模型
+	repoID, revision := splitRevision(name)
+	if err := validateDownload(platformOrPath, repoID, filePattern); err != nil {
+		return modelInfo{}, err
+	}
+	if revision != "" {
+		if err := validateRevision(revision); err != nil {
+			return modelInfo{}, err
 		}
-	} else {
-		// 如果是远程平台，使用 os/exec 调用 Python 下载模型
-		destPath = modelDir
-		errCopy = downloadModelWithPython(platformOrPath, name, modelDir, filePattern)
-		name = filepath.Join(name, filePattern)
 	}
-
-	if errCopy != nil {
-		return errCopy
+	if opts.LocalPath != "" {
+		return modelInfo{}, newError(errValidation, "--path is only supported for local models")
 	}
-
-	// 保存模型的元数据
-	metaPath := filepath.Join(modelDir, "models.json")
-	err = saveModelMetadata(metaPath, name, platformOrPath, destPath)
-	if err != nil {
-		return err
+	if filePattern != "" && (opts.Quant != "" || opts.FitMemory) {
+		return modelInfo{}, newError(errValidation, "specify either a file pattern or --quant/--fit-memory, not both")
 	}
-
-	return nil
-}
-
-// downloadModelWithPython 使用 os/exec 调用 Python 下载模型
-func downloadModelWithPython(platform, modelName, destPath, filePattern string) error {
-	// 创建 Python 脚本
-	pythonScript := fmt.Sprintf(`
-import subprocess
-import sys
-import importlib
-
-# 安装 pip（如果没有安装）
-def install_pip():
-    try:
-        subprocess.check_call([sys.executable, "-m", "ensurepip", "--upgrade"])
-    except subprocess.CalledProcessError:
-        print("Failed to install pip. Please install pip manually.")
-        # sys.exit(1) # just skip
-
-# 安装所需的库
-def install_libraries(libraries):
-    for lib in libraries:
-        try:
-            importlib.import_module(lib)
-        except ImportError:
-            print(f"Installing {lib}...")
-            subprocess.check_call([sys.executable, "-m", "pip", "install", lib])
-
-# 检查并安装库
-def check_and_install_libraries():
-    install_pip()
-    install_libraries(['huggingface_hub', 'modelscope'])
-
-# 下载模型
-def download_model(platform, model_name, dest_path, file_pattern):
-    if platform == 'huggingface':
-        from huggingface_hub import snapshot_download
-        snapshot_download(repo_id=model_name, cache_dir=dest_path, allow_patterns=file_pattern)
-    elif platform == 'modelscope':
-        from modelscope import snapshot_download
-        snapshot_download(model_name, cache_dir=dest_path, allow_file_pattern=file_pattern)
-    else:
-        print(f"Unsupported platform: {platform}")
-        return None
-
-check_and_install_libraries()
-download_model('%s', '%s', '%s', '%s')
-`, platform, modelName, destPath, filePattern)
-
-	// 使用 os/exec 执行 Python 脚本
-	cmd := exec.Command("python3", "-c", pythonScript)
-
-	// 设置输出管道
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("error creating stdout pipe: %v", err)
+	registryName := repoID
+	if filePattern != "" {
+		registryName = repoID + "/" + filePattern
+		// 指定分片模型的某一个分片时下载整组分片
+		filePattern = expandShardPattern(filePattern)
 	}
-
-	// 设置错误输出管道
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("error creating stderr pipe: %v", err)
+	selectFile := opts.Quant != "" || opts.FitMemory
+	if !selectFile {
+		if err := ensureNameAvailable(registryName); err != nil {
+			return modelInfo{}, err
+		}
 	}
 
-	// 启动命令
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("error starting command: %v", err)
+	// 记录 revision 对应的提交，用于之后检查更新
+	if revision == "" {
+		revision = defaultRevisions[platformOrPath]
+	}
+	commit, err := resolveRevision(platformOrPath, repoID, revision)
+	if err != nil {
+		warnf(opts.Logger, "could not resolve %s@%s to a commit: %v", repoID, revision, err)
 	}
+	downloadRev := downloadRevision(platformOrPath, revision, commit)
 
-	// 实时读取标准输出
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			fmt.Println(scanner.Text()) // 打印 Python 脚本的输出，包含进度信息
-		}
-		if err := scanner.Err(); err != nil {
-			fmt.Println("Error reading stdout:", err)
+	// 按量化类型或可用内存从仓库文件列表中选择模型
+	if selectFile {
+		files, err := listHubFiles(platformOrPath, repoID, downloadRev)
+		if err != nil {
+			return modelInfo{}, err
 		}
-	}()
-
-	// 实时读取标准错误输出
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			fmt.Println("Error:", scanner.Text()) // 打印 Python 脚本的错误输出
+		set, err := selectGGUFSet(files, opts.Quant, opts.FitMemory, opts.Logger)
+		if err != nil {
+			return modelInfo{}, err
 		}
-		if err := scanner.Err(); err != nil {
-			fmt.Println("Error reading stderr:", err)
+		filePattern = set.Pattern
+		registryName = repoID + "/" + set.Name
+		if err := ensureNameAvailable(registryName); err != nil {
+			return modelInfo{}, err
 		}
-	}()
-
-	// 等待命令完成
-	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("error downloading model using Python: %v", err)
 	}
 
-	return nil
-}
-
-// copyFile 复制本地文件
-func copyFile(src, dest string) error {
-	srcFile, err := os.Open(src)
+	// 下载的文件在注册之前不能被 rm 或 gc 删除
+	lease, err := newBlobLease()
 	if err != nil {
-		return err
+		return modelInfo{}, err
 	}
-	defer srcFile.Close()
-
-	destFile, err := os.Create(dest)
+	defer lease.release()
+	manifest, err := downloadHubFiles(ctx, platformOrPath, repoID, downloadRev, filePattern, opts, lease)
 	if err != nil {
-		return err
+		return modelInfo{}, err
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, srcFile)
-	return err
+	return registerModel(modelInfo{
+		Name:        registryName,
+		Platform:    platformOrPath,
+		Source:      repoID,
+		FilePattern: filePattern,
+		Revision:    revision,
+		Commit:      commit,
+		Files:       manifest,
+	})
 }
 
-// saveModelMetadata 保存模型的元数据到 models.json
-func saveModelMetadata(metaPath, name, platform, path string) error {
-	var models []map[string]string
+// addModelAsync 请求 serve 在后台下载远程模型，输出下载任务
+func addModelAsync(name, platform, filePattern string, opts addOptions) error {
+	if platform == "local" {
+		return newError(errValidation, "--async is only supported for Hugging Face and ModelScope")
+	}
+	if opts.LocalPath != "" {
+		return newError(errValidation, "--path is only supported for local models")
+	}
+	if opts.InstallDeps {
+		return newError(errValidation, "--install-deps cannot be combined with --async, the download runs in the Python environment of oneinfer serve")
+	}
 
-	// 读取现有的 models.json
-	if _, err := os.Stat(metaPath); err == nil {
-		file, err := os.ReadFile(metaPath)
-		if err == nil {
-			json.Unmarshal(file, &models)
-		}
+	download, err := apiClient().CreateDownload(context.Background(), client.DownloadRequest{
+		Platform:    platform,
+		Repo:        name,
+		FilePattern: filePattern,
+		Quant:       opts.Quant,
+		FitMemory:   opts.FitMemory,
+	})
+	if err != nil {
+		return clientError(err)
 	}
+	return printResult(download, func(w io.Writer) {
+		fmt.Fprintf(w, "Download %s of %s queued. Follow it with 'oneinfer downloads %s --follow'.\n", download.ID, name, download.ID)
+	})
+}
 
-	// 检查模型是否已经存在
-	for _, model := range models {
-		if model["name"] == name && model["platform"] == platform {
-			// 如果模型已存在，则跳过添加
-			fmt.Printf("Model '%s' already exists in the metadata.\n", name)
-			return nil
+// downloadHubFiles 把远程仓库下载到暂存目录，再移入 blob 仓库，返回文件清单。存入的 blob 记入 lease。
+func downloadHubFiles(ctx context.Context, platform, repoID, revision, filePattern string, opts addOptions, lease *blobLease) ([]modelFile, error) {
+	staging := opts.StagingDir
+	if staging == "" {
+		var err error
+		if staging, err = createStagingDir(); err != nil {
+			return nil, err
 		}
+		defer os.RemoveAll(staging)
+	} else if err := os.MkdirAll(staging, 0755); err != nil {
+		return nil, err
 	}
 
-	// 添加新模型
-	path = filepath.Join(path, name)
-	models = append(models, map[string]string{"name": name, "platform": platform, "path": path})
-	data, err := json.MarshalIndent(models, "", "  ")
+	if err := runDownloadScript(ctx, platform, repoID, revision, staging, filePattern, opts); err != nil {
+		return nil, err
+	}
+	files, err := collectModelFiles(staging)
 	if err != nil {
-		return err
+		return nil, newError(errNotFound, "no files matching %q were downloaded from %s", filePattern, repoID)
 	}
+	return ingestModelFiles(staging, files, blobMove, lease, opts.Logger)
+}
 
-	// 保存到文件
-	return os.WriteFile(metaPath, data, 0644)
+// createStagingDir 在 ~/.oneinfer/tmp 下创建下载暂存目录，与 blob 仓库位于同一文件系统
+func createStagingDir() (string, error) {
+	dir, err := oneinferDir()
+	if err != nil {
+		return "", err
+	}
+	tmp := filepath.Join(dir, "tmp")
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(tmp, "download-*")
 }
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"oneinfer/client"
+
+	"github.com/gorilla/mux"
+)
+
+// apiPrefix 版本化 REST API 的路径前缀
+const apiPrefix = "/api/v1"
+
+// serveStarted serve 的启动时间
+var serveStarted time.Time
+
+// apiError API 返回的 JSON 错误
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	Code      string `json:"code"` // HTTP 状态对应的错误码，如 not_found、conflict
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// daemonStatus GET /api/v1/daemon 的输出
+type daemonStatus struct {
+	Status        string    `json:"status"`
+	APIVersion    string    `json:"api_version"`
+	PID           int       `json:"pid"`
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	Instances     int       `json:"instances"`
+}
+
+// registerAPIRoutes 注册 /api/v1 下的接口：注册表中的模型、运行中的实例、下载、事件和服务本身。
+// 出错时统一返回 JSON 错误。
+func registerAPIRoutes(router *mux.Router) {
+	api := router.PathPrefix(apiPrefix).Subrouter()
+	api.Use(jsonErrorMiddleware)
+	api.NotFoundHandler = accessLogMiddleware(apiNotFoundHandler(api))
+	api.MethodNotAllowedHandler = api.NotFoundHandler
+
+	api.HandleFunc("/openapi.json", openAPIHandler).Methods("GET")
+
+	// 注册表中的模型，名称可能包含 "/"，/gguf 路由需要先注册
+	api.HandleFunc("/models", listAllModelHandler).Methods("GET")
+	api.HandleFunc("/models", addLibraryModelHandler).Methods("POST")
+	api.HandleFunc("/models/{name:.+}/gguf", libraryGGUFHandler).Methods("GET")
+	api.HandleFunc("/models/{name:.+}", getLibraryModelHandler).Methods("GET")
+	api.HandleFunc("/models/{name:.+}", updateLibraryModelHandler).Methods("PATCH")
+	api.HandleFunc("/models/{name:.+}", deleteLibraryModelHandler).Methods("DELETE")
+
+	// 运行中的实例
+	api.HandleFunc("/instances", listModelsHandler).Methods("GET")
+	api.HandleFunc("/instances", createInstanceHandler).Methods("POST")
+	api.HandleFunc("/instances/{id:[0-9]+}", getInstanceHandler).Methods("GET")
+	api.HandleFunc("/instances/{id:[0-9]+}", deleteInstanceHandler).Methods("DELETE")
+	api.HandleFunc("/instances/{id:[0-9]+}/logs", modelLogsHandler).Methods("GET")
+	api.HandleFunc("/instances/{id:[0-9]+}/v1/{path:.*}", modelProxyHandler)
+
+	// 下载和事件流
+	api.HandleFunc("/downloads", listDownloadsHandler).Methods("GET")
+	api.HandleFunc("/downloads", createDownloadHandler).Methods("POST")
+	api.HandleFunc("/downloads/{id}", getDownloadHandler).Methods("GET")
+	api.HandleFunc("/downloads/{id}", cancelDownloadHandler).Methods("DELETE")
+	api.HandleFunc("/events", eventsHandler).Methods("GET")
+	api.HandleFunc("/events", postEventHandler).Methods("POST")
+
+	// 服务本身
+	api.HandleFunc("/daemon", daemonHandler).Methods("GET")
+	api.HandleFunc("/daemon/shutdown", shutdownHandler).Methods("POST")
+}
+
+// apiNotFoundHandler 返回 404，路径存在但方法不对时返回 405 和 Allow 头。
+// gorilla/mux 的子路由在后面还有同前缀的路由时会丢失方法不匹配的信息，这里按其他方法重新匹配。
+func apiNotFoundHandler(api *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range []string{"GET", "POST", "PATCH", "DELETE"} {
+			req := r.Clone(r.Context())
+			req.Method = method
+			var match mux.RouteMatch
+			if api.Match(req, &match) && match.MatchErr == nil {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeAPIError(w, r, http.StatusMethodNotAllowed, fmt.Sprintf("method %s is not allowed for %s", r.Method, r.URL.Path))
+			return
+		}
+		writeAPIError(w, r, http.StatusNotFound, "no such endpoint: "+r.URL.Path)
+	})
+}
+
+// deprecatedRoute 标记旧的未版本化接口：响应带 Deprecation 头和指向 /api/v1 对应接口的 Link 头，
+// 每个路由第一次被调用时记录一条警告。
+func deprecatedRoute(oldPrefix, newPrefix string, h http.HandlerFunc) http.HandlerFunc {
+	var once sync.Once
+	return func(w http.ResponseWriter, r *http.Request) {
+		successor := newPrefix + strings.TrimPrefix(r.URL.Path, oldPrefix)
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		once.Do(func() {
+			requestLogger(r).Warn("deprecated API route used", "method", r.Method, "route", routeTemplate(r), "successor", successor)
+		})
+		h(w, r)
+	}
+}
+
+// errorCode 返回 HTTP 状态对应的错误码，如 404 对应 not_found
+func errorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// writeAPIError 以 JSON 返回错误
+func writeAPIError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	writeJSON(w, status, apiError{Error: apiErrorBody{Code: errorCode(status), Message: msg, RequestID: id}})
+}
+
+// jsonErrorMiddleware 把处理函数通过 http.Error 写出的纯文本错误改写为 JSON，
+// 新旧接口因此可以共用同一套处理函数
+func jsonErrorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jw := &jsonErrorWriter{ResponseWriter: w}
+		next.ServeHTTP(jw, r)
+		if jw.status != 0 {
+			w.Header().Del("X-Content-Type-Options")
+			writeAPIError(w, r, jw.status, strings.TrimSpace(jw.body.String()))
+		}
+	})
+}
+
+// jsonErrorWriter 拦截 http.Error 写出的错误响应，其他响应原样透传
+type jsonErrorWriter struct {
+	http.ResponseWriter
+	status int // 被拦截的错误状态码，0 表示未拦截
+	body   bytes.Buffer
+}
+
+func (w *jsonErrorWriter) WriteHeader(status int) {
+	h := w.Header()
+	// http.Error 会设置纯文本类型和 nosniff
+	if status >= 400 && strings.HasPrefix(h.Get("Content-Type"), "text/plain") && h.Get("X-Content-Type-Options") == "nosniff" {
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *jsonErrorWriter) Write(p []byte) (int, error) {
+	if w.status != 0 {
+		return w.body.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *jsonErrorWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && w.status == 0 {
+		f.Flush()
+	}
+}
+
+// openAPIHandler 返回内嵌的 OpenAPI 文档
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := staticFiles.ReadFile("static/openapi.json")
+	if err != nil {
+		http.Error(w, "Could not load openapi.json", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// createInstanceHandler 启动模型实例，返回 201 和新实例的状态
+func createInstanceHandler(w http.ResponseWriter, r *http.Request) {
+	modelMux.Lock()
+	defer modelMux.Unlock()
+
+	mp := startInstance(w, r)
+	if mp == nil {
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("%s/instances/%d", apiPrefix, mp.ID))
+	writeJSON(w, http.StatusCreated, mp.status())
+}
+
+// getInstanceHandler 返回一个实例的状态
+func getInstanceHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	modelMux.Lock()
+	defer modelMux.Unlock()
+	mp, ok := models[id]
+	if !ok {
+		http.Error(w, "Process not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, mp.status())
+}
+
+// deleteInstanceHandler 停止实例，返回停止时的状态
+func deleteInstanceHandler(w http.ResponseWriter, r *http.Request) {
+	modelMux.Lock()
+	defer modelMux.Unlock()
+
+	if mp := stopInstance(w, r); mp != nil {
+		writeJSON(w, http.StatusOK, mp.status())
+	}
+}
+
+// daemonHandler 返回 serve 的运行状态
+func daemonHandler(w http.ResponseWriter, r *http.Request) {
+	modelMux.Lock()
+	instances := len(models)
+	modelMux.Unlock()
+
+	writeJSON(w, http.StatusOK, daemonStatus{
+		Status:        "ok",
+		APIVersion:    strings.TrimPrefix(apiPrefix, "/api/"),
+		PID:           os.Getpid(),
+		StartedAt:     serveStarted.UTC(),
+		UptimeSeconds: int64(time.Since(serveStarted).Seconds()),
+		Instances:     instances,
+	})
+}
+
+// shutdownHandler 停止所有模型并退出 serve
+func shutdownHandler(w http.ResponseWriter, r *http.Request) {
+	stopAllModels(r)
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "stopping"})
+	exitAfterResponse(w)
+}
+
+// apiClient 返回 CLI 访问 serve 的客户端，地址和令牌可以用 ONEINFER_HOST、ONEINFER_API_TOKEN 覆盖
+func apiClient() *client.Client {
+	return client.New(os.Getenv("ONEINFER_HOST"),
+		client.WithToken(os.Getenv("ONEINFER_API_TOKEN")),
+		client.WithUserAgent("oneinfer-cli"))
+}
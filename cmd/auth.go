@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// tokenMiddleware 设置了令牌时，要求 GET、HEAD 以外的请求带有 Authorization: Bearer <token>。
+// 只读接口和网页不需要令牌。
+func tokenMiddleware(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if token == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isReadOnly(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="oneinfer"`)
+				rejectRequest(w, r, http.StatusUnauthorized, "a valid API token is required: send Authorization: Bearer <token>")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// browserGuardMiddleware 拦截浏览器中其他网页发来的请求：
+// 只在回环地址上监听时要求 Host 为回环地址，防止 DNS 重绑定；
+// GET、HEAD 以外的请求不能来自其他来源，带请求体时必须是 application/json，
+// 这样跨站的 text/plain 或表单“简单请求”无法修改状态。
+func browserGuardMiddleware(loopback bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if loopback && !isLoopbackHost(r.Host) {
+				rejectRequest(w, r, http.StatusForbidden, fmt.Sprintf("host %q is not allowed: the service only accepts requests for localhost", r.Host))
+				return
+			}
+			if isReadOnly(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !isSameOrigin(r) {
+				rejectRequest(w, r, http.StatusForbidden, "cross-origin requests that change something are not allowed")
+				return
+			}
+			if r.ContentLength != 0 {
+				mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if mediaType != "application/json" {
+					rejectRequest(w, r, http.StatusUnsupportedMediaType, "request body must be sent as Content-Type: application/json")
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rejectRequest 拒绝请求：/api/v1 下返回 JSON 错误，其他路径返回纯文本
+func rejectRequest(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	if strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+		writeAPIError(w, r, status, msg)
+	} else {
+		http.Error(w, msg, status)
+	}
+}
+
+// isReadOnly 判断请求是否为只读的 GET 或 HEAD
+func isReadOnly(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// isLoopbackHost 判断 Host 头是否指向回环地址，端口可省略
+func isLoopbackHost(host string) bool {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "0")
+	}
+	return isLoopbackAddr(host)
+}
+
+// isSameOrigin 判断请求是否来自服务自己的网页或非浏览器客户端。
+// 浏览器发出的跨站请求会带上其他来源的 Origin 或 Sec-Fetch-Site: cross-site/same-site。
+func isSameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTokenMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	router.HandleFunc(apiPrefix+"/models", ok)
+	router.HandleFunc("/stop", ok)
+	router.Use(tokenMiddleware("s3cret"))
+
+	tests := []struct {
+		method, path, auth string
+		want               int
+	}{
+		{"GET", apiPrefix + "/models", "", http.StatusNoContent},
+		{"HEAD", apiPrefix + "/models", "", http.StatusNoContent},
+		{"POST", apiPrefix + "/models", "", http.StatusUnauthorized},
+		{"DELETE", apiPrefix + "/models", "Bearer wrong", http.StatusUnauthorized},
+		{"PATCH", apiPrefix + "/models", "s3cret", http.StatusUnauthorized},
+		{"POST", apiPrefix + "/models", "Bearer s3cret", http.StatusNoContent},
+		{"POST", "/stop", "", http.StatusUnauthorized},
+		{"POST", "/stop", "Bearer s3cret", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s (%q) = %d, want %d", tt.method, tt.path, tt.auth, rec.Code, tt.want)
+		}
+	}
+
+	// /api/v1 下的错误为 JSON
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", apiPrefix+"/models", nil))
+	var body apiError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Code != "unauthorized" {
+		t.Errorf("error body = %s", rec.Body)
+	}
+	if rec.Header().Get("WWW-Authenticate") == "" {
+		t.Error("missing WWW-Authenticate header")
+	}
+
+	// 未设置令牌时不检查
+	open := tokenMiddleware("")(http.HandlerFunc(ok))
+	rec = httptest.NewRecorder()
+	open.ServeHTTP(rec, httptest.NewRequest("DELETE", apiPrefix+"/models", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("without a token: status %d", rec.Code)
+	}
+}
+
+func TestBrowserGuardMiddleware(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	guarded := browserGuardMiddleware(true)(http.HandlerFunc(ok))
+
+	tests := []struct {
+		name, method, host, body string
+		header                   map[string]string
+		want                     int
+	}{
+		{"read", "GET", "127.0.0.1:9090", "", nil, http.StatusNoContent},
+		{"localhost", "GET", "localhost:9090", "", nil, http.StatusNoContent},
+		{"ipv6", "GET", "[::1]:9090", "", nil, http.StatusNoContent},
+		{"no port", "GET", "localhost", "", nil, http.StatusNoContent},
+		{"cli", "POST", "127.0.0.1:9090", "{}", map[string]string{"Content-Type": "application/json"}, http.StatusNoContent},
+		{"no body", "POST", "127.0.0.1:9090", "", nil, http.StatusNoContent},
+		{"same origin", "DELETE", "127.0.0.1:9090", "", map[string]string{"Origin": "http://127.0.0.1:9090", "Sec-Fetch-Site": "same-origin"}, http.StatusNoContent},
+		{"typed url", "POST", "127.0.0.1:9090", "", map[string]string{"Sec-Fetch-Site": "none"}, http.StatusNoContent},
+		{"json charset", "PATCH", "127.0.0.1:9090", "{}", map[string]string{"Content-Type": "application/json; charset=utf-8"}, http.StatusNoContent},
+
+		// DNS 重绑定：域名解析到 127.0.0.1，但 Host 仍是攻击者的域名
+		{"rebinding", "GET", "attacker.example:9090", "", nil, http.StatusForbidden},
+		{"rebinding post", "POST", "attacker.example", "", nil, http.StatusForbidden},
+
+		// 其他网站的网页发出的请求
+		{"foreign origin", "POST", "127.0.0.1:9090", "", map[string]string{"Origin": "https://attacker.example"}, http.StatusForbidden},
+		{"null origin", "POST", "127.0.0.1:9090", "", map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"other port", "DELETE", "127.0.0.1:9090", "", map[string]string{"Origin": "http://127.0.0.1:8000"}, http.StatusForbidden},
+		{"cross-site", "POST", "127.0.0.1:9090", "", map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"same-site", "POST", "127.0.0.1:9090", "", map[string]string{"Sec-Fetch-Site": "same-site"}, http.StatusForbidden},
+
+		// 跨站“简单请求”只能使用 text/plain、表单或 multipart
+		{"text/plain", "POST", "127.0.0.1:9090", `{"model":"x"}`, map[string]string{"Content-Type": "text/plain"}, http.StatusUnsupportedMediaType},
+		{"form", "POST", "127.0.0.1:9090", "a=b", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, http.StatusUnsupportedMediaType},
+		{"no content type", "POST", "127.0.0.1:9090", "{}", nil, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, apiPrefix+"/models", strings.NewReader(tt.body))
+		req.Host = tt.host
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		guarded.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: %s %s = %d, want %d (%s)", tt.name, tt.method, tt.host, rec.Code, tt.want, rec.Body)
+		}
+	}
+
+	// 监听其他地址时不限制 Host
+	open := browserGuardMiddleware(false)(http.HandlerFunc(ok))
+	req := httptest.NewRequest("GET", apiPrefix+"/models", nil)
+	req.Host = "gpu-box:9090"
+	rec := httptest.NewRecorder()
+	open.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("non-loopback listener: Host gpu-box rejected with %d", rec.Code)
+	}
+	req = httptest.NewRequest("POST", apiPrefix+"/models", nil)
+	req.Host = "gpu-box:9090"
+	req.Header.Set("Origin", "https://attacker.example")
+	rec = httptest.NewRecorder()
+	open.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("non-loopback listener: foreign origin = %d, want 403", rec.Code)
+	}
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1:9090", true},
+		{"localhost:9090", true},
+		{"[::1]:9090", true},
+		{"0.0.0.0:9090", false},
+		{":9090", false},
+		{"192.168.1.10:9090", false},
+		{"gpu-box:9090", false},
+	}
+	for _, tt := range tests {
+		if got := isLoopbackAddr(tt.addr); got != tt.want {
+			t.Errorf("isLoopbackAddr(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
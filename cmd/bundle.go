@@ -0,0 +1,408 @@
+package cmd
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// .oitar 包是一个 tar 文件：
+//
+//	oneinfer-bundle.json      包清单，包含模型记录（含文件清单和默认运行参数）
+//	blobs/sha256-<hex>        模型文件，按摘要命名，相同内容只保存一份
+//
+// 清单总是第一个条目，导入时可以边读边校验，支持通过管道传输。
+
+const (
+	bundleManifestName = "oneinfer-bundle.json"
+	bundleVersion      = 1
+)
+
+// bundleManifest 包清单
+type bundleManifest struct {
+	Version  int       `json:"version"`
+	Exported string    `json:"exported"`
+	Model    modelInfo `json:"model"`
+}
+
+// exportOutput export 命令的输出结构
+type exportOutput struct {
+	Name   string `json:"name"`
+	Output string `json:"output"`
+	Files  int    `json:"files"`
+	Size   int64  `json:"size"`
+}
+
+// importOutput import 命令的输出结构
+type importOutput struct {
+	Models  []modelInfo `json:"models"`
+	Skipped []string    `json:"skipped,omitempty"` // 名称已存在而跳过的模型
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export <model_name>",
+	Short: "Export a model with its files and default run parameters as a portable .oitar bundle",
+	Long: `Export a model with its files and default run parameters as a portable .oitar bundle.
+Use "-o -" (the default when stdout is not a terminal) to stream the bundle, e.g.
+
+  oneinfer export qwen -o - | ssh host oneinfer import -`,
+	Args:              validArgs(cobra.ExactArgs(1)),
+	ValidArgsFunction: completeModelNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output-file")
+		if output == "" {
+			if isTerminal(os.Stdout) {
+				return newError(errValidation, "refusing to write a bundle to the terminal, use -o <file> or redirect stdout")
+			}
+			output = "-"
+		}
+
+		model, err := findModel(args[0])
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "-" {
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := exportModel(model, w); err != nil {
+			if output != "-" {
+				os.Remove(output)
+			}
+			return err
+		}
+
+		out := exportOutput{Name: model.Name, Output: output, Files: len(model.Files), Size: model.Size}
+		// 包写到标准输出时，结果只能输出到标准错误
+		if output == "-" {
+			fmt.Fprintf(os.Stderr, "Model '%s' exported (%s).\n", out.Name, formatBytes(out.Size))
+			return nil
+		}
+		return printResult(out, func(w io.Writer) {
+			fmt.Fprintf(w, "Model '%s' exported to %s (%s).\n", out.Name, out.Output, formatBytes(out.Size))
+		})
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <bundle.oitar|-> | import --from ollama|hf-cache [path]",
+	Short: "Import a model from a .oitar bundle, or the models in an Ollama or Hugging Face cache",
+	Long: `Import a model from a .oitar bundle, verifying the checksum of every file ("-" reads from stdin).
+
+With --from, scan an existing Ollama (~/.ollama/models) or Hugging Face (~/.cache/huggingface/hub)
+cache and register its models in place with --mode symlink (default) or hardlink, without
+downloading them again.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if from, _ := cmd.Flags().GetString("from"); from != "" {
+			return validArgs(cobra.MaximumNArgs(1))(cmd, args)
+		}
+		return validArgs(cobra.ExactArgs(1))(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("name")
+		from, _ := cmd.Flags().GetString("from")
+		mode, _ := cmd.Flags().GetString("mode")
+
+		var out importOutput
+		if from != "" {
+			if name != "" {
+				return newError(errValidation, "--name cannot be used with --from")
+			}
+			var dir string
+			if len(args) > 0 {
+				dir = args[0]
+			}
+			models, skipped, err := importFromCache(from, dir, mode)
+			if err != nil {
+				return err
+			}
+			out = importOutput{Models: models, Skipped: skipped}
+		} else {
+			var r io.Reader = os.Stdin
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return wrapError(errValidation, err, "cannot open bundle")
+				}
+				defer f.Close()
+				r = f
+			}
+			model, err := importBundle(r, name, nil)
+			if err != nil {
+				return err
+			}
+			out = importOutput{Models: []modelInfo{model}}
+		}
+
+		return printResult(out, func(w io.Writer) {
+			for _, m := range out.Models {
+				fmt.Fprintf(w, "Model '%s' imported successfully!\n", m.Name)
+			}
+			for _, name := range out.Skipped {
+				fmt.Fprintf(w, "Model '%s' already exists, skipped.\n", name)
+			}
+		})
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringP("output-file", "o", "", "Bundle file to write, \"-\" for stdout")
+	importCmd.Flags().String("name", "", "Register the model under a different name")
+	importCmd.Flags().String("from", "", "Import the models in an existing cache: ollama or hf-cache")
+	importCmd.Flags().String("mode", importSymlink, "How to link cached files with --from: symlink or hardlink")
+	importCmd.RegisterFlagCompletionFunc("from", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return cacheSources, cobra.ShellCompDirectiveNoFileComp
+	})
+	importCmd.RegisterFlagCompletionFunc("mode", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{importSymlink, importHardlink}, cobra.ShellCompDirectiveNoFileComp
+	})
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+// isTerminal 判断文件是否为终端
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// exportModel 把模型写成 .oitar 包
+func exportModel(model modelInfo, w io.Writer) error {
+	if model.Files == nil {
+		return newError(errValidation, "model '%s' was added by an older version of oneinfer and has no file manifest, add it again to export it", model.Name)
+	}
+
+	// 本机相关的字段在导入时重新生成
+	exported := model
+	exported.Path, exported.Mmproj, exported.Mode, exported.AddedDate = "", "", "", ""
+	exported.Files = make([]modelFile, len(model.Files))
+	for i, f := range model.Files {
+		f.External = ""
+		exported.Files[i] = f
+	}
+	manifest, err := json.MarshalIndent(bundleManifest{
+		Version:  bundleVersion,
+		Exported: time.Now().UTC().Format(time.RFC3339),
+		Model:    exported,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    bundleManifestName,
+		Mode:    0644,
+		Size:    int64(len(manifest)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+
+	written := map[string]bool{}
+	for _, f := range model.Files {
+		if written[f.Digest] {
+			continue
+		}
+		written[f.Digest] = true
+		if err := writeBundleBlob(tw, f); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// writeBundleBlob 把清单中的一个文件写入包，文件大小必须与清单一致
+func writeBundleBlob(tw *tar.Writer, f modelFile) error {
+	src, err := f.filePath()
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %v (run oneinfer verify --repair)", f.Path, err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != f.Size {
+		return fmt.Errorf("%s has size %d, expected %d (run oneinfer verify --repair)", f.Path, info.Size(), f.Size)
+	}
+	fmt.Fprintf(os.Stderr, "Exporting %s (%s)...\n", f.Path, formatBytes(f.Size))
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    "blobs/" + strings.Replace(f.Digest, ":", "-", 1),
+		Mode:    0644,
+		Size:    f.Size,
+		ModTime: info.ModTime(),
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, in)
+	return err
+}
+
+// importBundle 读取 .oitar 包，逐个校验文件摘要后存入 blob 仓库并注册模型，提示和警告输出到 log
+func importBundle(r io.Reader, name string, log *slog.Logger) (modelInfo, error) {
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	if err != nil {
+		return modelInfo{}, newError(errValidation, "not a oneinfer bundle: %v", err)
+	}
+	if hdr.Name != bundleManifestName {
+		return modelInfo{}, newError(errValidation, "not a oneinfer bundle: first entry is %q, expected %s", hdr.Name, bundleManifestName)
+	}
+	var manifest bundleManifest
+	if err := json.NewDecoder(io.LimitReader(tr, 16<<20)).Decode(&manifest); err != nil {
+		return modelInfo{}, newError(errValidation, "invalid bundle manifest: %v", err)
+	}
+	if manifest.Version != bundleVersion {
+		return modelInfo{}, newError(errValidation, "unsupported bundle version %d", manifest.Version)
+	}
+
+	model := manifest.Model
+	if name != "" {
+		model.Name = name
+	}
+	if model.Name == "" || len(model.Files) == 0 {
+		return modelInfo{}, newError(errValidation, "invalid bundle manifest: missing model name or files")
+	}
+	// 模型名会作为视图目录名
+	if strings.HasPrefix(model.Name, ".") || strings.Contains(model.Name, "..") || strings.ContainsAny(model.Name, "\\\x00") {
+		return modelInfo{}, newError(errValidation, "invalid model name %q", model.Name)
+	}
+	for _, f := range model.Files {
+		if f.External != "" || !filepath.IsLocal(filepath.FromSlash(f.Path)) {
+			return modelInfo{}, newError(errValidation, "invalid bundle manifest: bad file path %q", f.Path)
+		}
+		if _, err := blobPath(f.Digest); err != nil {
+			return modelInfo{}, newError(errValidation, "invalid bundle manifest: %v", err)
+		}
+	}
+	if err := ensureNameAvailable(model.Name); err != nil {
+		return modelInfo{}, err
+	}
+	model.Aliases = availableAliases(model.Aliases, log)
+
+	// 包中的文件按摘要校验后存入 blob 仓库
+	expected := map[string]int64{}
+	for _, f := range model.Files {
+		expected[f.Digest] = f.Size
+	}
+	lease, err := newBlobLease()
+	if err != nil {
+		return modelInfo{}, err
+	}
+	defer lease.release()
+	stored, err := readBundleBlobs(tr, expected, lease)
+	if err != nil {
+		removeImportedBlobs(lease, stored)
+		return modelInfo{}, err
+	}
+	for digest := range expected {
+		if !containsString(stored, digest) {
+			removeImportedBlobs(lease, stored)
+			return modelInfo{}, newError(errValidation, "bundle is incomplete: %s is missing", digest)
+		}
+	}
+
+	model.Mode = ""
+	// 本地模型的原始路径属于导出包的机器，verify --repair 不能在这台机器上使用它
+	if !isSupportedPlatform(model.Platform) {
+		model.Source = ""
+	}
+	registered, err := registerModel(model)
+	if err != nil {
+		removeImportedBlobs(lease, stored)
+		return modelInfo{}, err
+	}
+	return registered, nil
+}
+
+// readBundleBlobs 读取包中的 blob 条目，返回已存入仓库的摘要。存入的 blob 记入 lease。
+func readBundleBlobs(tr *tar.Reader, expected map[string]int64, lease *blobLease) ([]string, error) {
+	dir, err := blobsDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var stored []string
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return stored, nil
+		}
+		if err != nil {
+			return stored, newError(errValidation, "corrupt bundle: %v", err)
+		}
+		name, ok := strings.CutPrefix(hdr.Name, "blobs/")
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		digest := strings.Replace(name, "-", ":", 1)
+		size, ok := expected[digest]
+		if !ok || containsString(stored, digest) {
+			continue
+		}
+		if hdr.Size != size {
+			return stored, newError(errValidation, "corrupt bundle: %s has size %d, expected %d", digest, hdr.Size, size)
+		}
+
+		fmt.Fprintf(infoOut(), "Importing %s (%s)...\n", digest, formatBytes(size))
+		if _, err := writeBlob(tr, dir, digest, lease); err != nil {
+			return stored, newError(errValidation, "corrupt bundle: %v", err)
+		}
+		stored = append(stored, digest)
+	}
+}
+
+// removeImportedBlobs 导入失败时释放租约，删除本次写入且未被其他模型引用的 blob
+func removeImportedBlobs(lease *blobLease, digests []string) {
+	lease.release()
+	files := make([]modelFile, 0, len(digests))
+	for _, d := range digests {
+		files = append(files, modelFile{Digest: d})
+	}
+	pruneBlobs(files)
+}
+
+// availableAliases 去掉格式无效或已被其他模型占用的别名，跳过的别名作为警告输出到 log
+func availableAliases(aliases []string, log *slog.Logger) []string {
+	var result []string
+	for _, alias := range aliases {
+		if !aliasPattern.MatchString(alias) {
+			warnf(log, "invalid alias %q, skipping it", alias)
+			continue
+		}
+		if _, err := findModel(alias); err == nil {
+			warnf(log, "alias '%s' is already in use, skipping it", alias)
+			continue
+		}
+		result = append(result, alias)
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 导入时丢弃无效的别名，并且不保留导出机器上的本地原始路径
+func TestImportBundleSanitizesModel(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := filepath.Join(t.TempDir(), "m.gguf")
+	os.WriteFile(src, []byte("GGUF bundle"), 0644)
+	model, err := importLocalModel("m", src, importCopy, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.Aliases = []string{"good", "bad alias", "../escape"}
+	var bundle bytes.Buffer
+	if err := exportModel(model, &bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	// 在另一台机器上导入
+	t.Setenv("HOME", t.TempDir())
+	var logs bytes.Buffer
+	imported, err := importBundle(&bundle, "", slog.New(slog.NewTextHandler(&logs, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(imported.Aliases, []string{"good"}) {
+		t.Errorf("aliases = %v, want [good]", imported.Aliases)
+	}
+	if !strings.Contains(logs.String(), "bad alias") {
+		t.Errorf("no warning about the invalid alias: %s", logs.String())
+	}
+	if imported.Source != "" {
+		t.Errorf("source = %q, want the exporting machine's path dropped", imported.Source)
+	}
+}
@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 可以导入的本地缓存
+const (
+	cacheOllama  = "ollama"
+	cacheHFCache = "hf-cache"
+)
+
+// cacheSources 支持的缓存类型
+var cacheSources = []string{cacheOllama, cacheHFCache}
+
+// Ollama 清单中的层类型
+const (
+	ollamaModelLayer     = "application/vnd.ollama.image.model"
+	ollamaProjectorLayer = "application/vnd.ollama.image.projector"
+	ollamaDefaultHost    = "registry.ollama.ai"
+)
+
+// HF 缓存中按 sha256 命名的 blob（LFS 文件），其他文件以 git sha1 命名
+var sha256HexPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ollamaManifest Ollama 的模型清单
+type ollamaManifest struct {
+	Layers []struct {
+		MediaType string `json:"mediaType"`
+		Digest    string `json:"digest"`
+		Size      int64  `json:"size"`
+	} `json:"layers"`
+}
+
+// cacheModel 在缓存中找到的一个模型
+type cacheModel struct {
+	info  modelInfo
+	files []cacheFile
+}
+
+// cacheFile 缓存中的一个文件，digest 为空时导入时计算
+type cacheFile struct {
+	path   string // 视图目录中的相对路径
+	src    string // 缓存中的实际位置
+	digest string
+	size   int64
+}
+
+// validateCacheSource 校验缓存类型和导入方式
+func validateCacheSource(source, mode string) error {
+	if !containsString(cacheSources, source) {
+		return newError(errValidation, "unsupported cache %q: expected one of %s", source, strings.Join(cacheSources, ", "))
+	}
+	if mode != importSymlink && mode != importHardlink {
+		return newError(errValidation, "invalid import mode %q: models are imported from caches with symlink or hardlink", mode)
+	}
+	return nil
+}
+
+// defaultCacheDir 返回缓存的默认位置，遵循 OLLAMA_MODELS、HF_HUB_CACHE 和 HF_HOME 环境变量
+func defaultCacheDir(source string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch source {
+	case cacheOllama:
+		if dir := os.Getenv("OLLAMA_MODELS"); dir != "" {
+			return dir, nil
+		}
+		return filepath.Join(homeDir, ".ollama", "models"), nil
+	default:
+		if dir := os.Getenv("HF_HUB_CACHE"); dir != "" {
+			return dir, nil
+		}
+		if dir := os.Getenv("HF_HOME"); dir != "" {
+			return filepath.Join(dir, "hub"), nil
+		}
+		return filepath.Join(homeDir, ".cache", "huggingface", "hub"), nil
+	}
+}
+
+// importFromCache 扫描 Ollama 或 Hugging Face 缓存，把其中的模型以链接方式注册，
+// 返回新注册的模型和因名称已存在而跳过的模型
+func importFromCache(source, dir, mode string) ([]modelInfo, []string, error) {
+	if err := validateCacheSource(source, mode); err != nil {
+		return nil, nil, err
+	}
+	if dir == "" {
+		var err error
+		if dir, err = defaultCacheDir(source); err != nil {
+			return nil, nil, err
+		}
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, nil, wrapError(errNotFound, err, "%s cache not found", source)
+	}
+
+	var found []cacheModel
+	var err error
+	if source == cacheOllama {
+		found, err = scanOllama(dir)
+	} else {
+		found, err = scanHFCache(dir)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(found) == 0 {
+		return nil, nil, newError(errNotFound, "no models found in %s", dir)
+	}
+
+	lease, err := newBlobLease()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lease.release()
+
+	imported := []modelInfo{}
+	var skipped []string
+	for _, m := range found {
+		if err := ensureNameAvailable(m.info.Name); err != nil {
+			if kindOf(err) != errConflict {
+				return imported, skipped, err
+			}
+			skipped = append(skipped, m.info.Name)
+			continue
+		}
+
+		manifest := make([]modelFile, 0, len(m.files))
+		for _, f := range m.files {
+			fmt.Fprintf(infoOut(), "Importing %s %s (%s)...\n", m.info.Name, f.path, mode)
+			file, err := linkCacheFile(f, mode, lease)
+			if err != nil {
+				return imported, skipped, err
+			}
+			manifest = append(manifest, file)
+		}
+		m.info.Mode = mode
+		m.info.Files = manifest
+		model, err := registerModel(m.info)
+		if err != nil {
+			return imported, skipped, err
+		}
+		imported = append(imported, model)
+	}
+	return imported, skipped, nil
+}
+
+// linkCacheFile 把缓存中的文件链接到 blob 仓库（hardlink）或记录为外部文件（symlink）。
+// 缓存已经按内容寻址时直接使用其摘要，不再重新计算。链接的 blob 记入 lease。
+func linkCacheFile(f cacheFile, mode string, lease *blobLease) (modelFile, error) {
+	file := modelFile{Path: f.path, Digest: f.digest, Size: f.size}
+	if file.Digest == "" {
+		var err error
+		if file.Digest, file.Size, err = hashFile(f.src); err != nil {
+			return modelFile{}, err
+		}
+	}
+
+	if mode == importSymlink {
+		file.External = f.src
+		return file, nil
+	}
+
+	blob, err := blobPath(file.Digest)
+	if err != nil {
+		return modelFile{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+		return modelFile{}, err
+	}
+	if _, err := commitBlob(lease, file.Digest, func(blob string) error {
+		if err := os.Link(f.src, blob); err != nil {
+			return fmt.Errorf("failed to hardlink %s (is it on the same filesystem? use --mode symlink): %v", f.src, err)
+		}
+		return nil
+	}); err != nil {
+		return modelFile{}, err
+	}
+	return file, nil
+}
+
+// scanOllama 扫描 Ollama 的 manifests/<host>/<namespace>/<model>/<tag> 清单
+func scanOllama(dir string) ([]cacheModel, error) {
+	manifests := filepath.Join(dir, "manifests")
+	var found []cacheModel
+	err := filepath.Walk(manifests, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(manifests, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) != 4 {
+			return nil
+		}
+		m, err := readOllamaManifest(dir, path, parts[0], parts[1], parts[2], parts[3])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", rel, err)
+			return nil
+		}
+		found = append(found, m)
+		return nil
+	})
+	if err != nil {
+		return nil, wrapError(errNotFound, err, "cannot read Ollama manifests")
+	}
+	return found, nil
+}
+
+// readOllamaManifest 读取一个 Ollama 清单，返回其中的模型和 projector 层
+func readOllamaManifest(dir, path, host, namespace, model, tag string) (cacheModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cacheModel{}, err
+	}
+	var manifest ollamaManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return cacheModel{}, fmt.Errorf("invalid manifest: %v", err)
+	}
+	manifestDigest, _, err := hashFile(path)
+	if err != nil {
+		return cacheModel{}, err
+	}
+
+	// 与 ollama 命令中的写法一致：官方库省略 host 和 namespace
+	ref := model + ":" + tag
+	if namespace != "library" || host != ollamaDefaultHost {
+		ref = namespace + "/" + ref
+	}
+	if host != ollamaDefaultHost {
+		ref = host + "/" + ref
+	}
+
+	m := cacheModel{info: modelInfo{
+		Name:     "ollama/" + ref,
+		Platform: cacheOllama,
+		Source:   host + "/" + namespace + "/" + model,
+		Revision: tag,
+		Commit:   manifestDigest,
+	}}
+	for _, layer := range manifest.Layers {
+		var name string
+		switch layer.MediaType {
+		case ollamaModelLayer:
+			name = model + "-" + tag + ".gguf"
+		case ollamaProjectorLayer:
+			name = "mmproj-" + model + "-" + tag + ".gguf"
+		default:
+			continue
+		}
+		blob := filepath.Join(dir, "blobs", strings.Replace(layer.Digest, ":", "-", 1))
+		if _, err := blobPath(layer.Digest); err != nil {
+			return cacheModel{}, err
+		}
+		if _, err := os.Stat(blob); err != nil {
+			return cacheModel{}, fmt.Errorf("blob %s is missing", layer.Digest)
+		}
+		m.files = append(m.files, cacheFile{path: name, src: blob, digest: layer.Digest, size: layer.Size})
+	}
+	if len(m.files) == 0 {
+		return cacheModel{}, fmt.Errorf("no model layer")
+	}
+	return m, nil
+}
+
+// scanHFCache 扫描 Hugging Face 缓存中的 models--<owner>--<name> 目录，
+// 每个仓库导入 refs/main 指向的快照，没有 main 时导入最新的快照
+func scanHFCache(dir string) ([]cacheModel, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, wrapError(errNotFound, err, "cannot read Hugging Face cache")
+	}
+
+	var found []cacheModel
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), "models--")
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		repoID := strings.Replace(name, "--", "/", 1)
+		m, err := readHFSnapshot(filepath.Join(dir, entry.Name()), repoID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", repoID, err)
+			continue
+		}
+		found = append(found, m)
+	}
+	return found, nil
+}
+
+// readHFSnapshot 读取仓库的一个快照
+func readHFSnapshot(repoDir, repoID string) (cacheModel, error) {
+	revision := defaultRevisions["huggingface"]
+	commit := ""
+	if data, err := os.ReadFile(filepath.Join(repoDir, "refs", revision)); err == nil {
+		commit = strings.TrimSpace(string(data))
+	} else {
+		// 没有 main 引用时取最新的快照，按提交记录
+		snapshots, err := os.ReadDir(filepath.Join(repoDir, "snapshots"))
+		if err != nil || len(snapshots) == 0 {
+			return cacheModel{}, fmt.Errorf("no snapshots")
+		}
+		var latest os.FileInfo
+		for _, s := range snapshots {
+			info, err := s.Info()
+			if err == nil && (latest == nil || info.ModTime().After(latest.ModTime())) {
+				latest = info
+			}
+		}
+		if latest == nil {
+			return cacheModel{}, fmt.Errorf("no snapshots")
+		}
+		commit = latest.Name()
+		revision = commit
+	}
+	if !commitPattern.MatchString(commit) {
+		return cacheModel{}, fmt.Errorf("invalid commit %q", commit)
+	}
+
+	snapshot := filepath.Join(repoDir, "snapshots", commit)
+	paths, err := collectModelFiles(snapshot)
+	if err != nil || len(paths) == 0 {
+		return cacheModel{}, fmt.Errorf("snapshot %s is empty", commit)
+	}
+
+	// 快照中的文件是指向 ../blobs/<etag> 的链接，LFS 文件的 etag 就是 sha256
+	m := cacheModel{info: modelInfo{
+		Platform: "huggingface",
+		Source:   repoID,
+		Revision: revision,
+		Commit:   commit,
+	}}
+	var hubFiles []hubFile
+	for _, rel := range paths {
+		src, err := filepath.EvalSymlinks(filepath.Join(snapshot, rel))
+		if err != nil {
+			return cacheModel{}, fmt.Errorf("%s: %v", rel, err)
+		}
+		info, err := os.Stat(src)
+		if err != nil {
+			return cacheModel{}, err
+		}
+		f := cacheFile{path: filepath.ToSlash(rel), src: src, size: info.Size()}
+		if sha256HexPattern.MatchString(filepath.Base(src)) {
+			f.digest = "sha256:" + filepath.Base(src)
+		}
+		m.files = append(m.files, f)
+		hubFiles = append(hubFiles, hubFile{Path: f.path, Size: f.size})
+	}
+	sort.Slice(m.files, func(i, j int) bool { return m.files[i].path < m.files[j].path })
+
+	// 快照中只有一个 GGUF 模型时按该模型命名，pull 时也只下载这些文件
+	m.info.Name = repoID
+	if sets := groupGGUFSets(hubFiles); len(sets) == 1 && len(sets[0].Files) == len(hubFiles) {
+		m.info.Name = repoID + "/" + sets[0].Name
+		m.info.FilePattern = sets[0].Pattern
+	}
+	return m, nil
+}
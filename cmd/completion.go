@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// completeModelNames 补全注册表中的模型名，只补全第一个参数
+func completeModelNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	models, err := listModels()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var names []string
+	for _, model := range models {
+		if strings.HasPrefix(model.Name, toComplete) {
+			names = append(names, model.Name+"\t"+model.Platform)
+		}
+		for _, alias := range model.Aliases {
+			if strings.HasPrefix(alias, toComplete) {
+				names = append(names, alias+"\talias of "+model.Name)
+			}
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeLabels 补全注册表中已使用的标签
+func completeLabels(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	models, err := listModels()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	seen := map[string]bool{}
+	var labels []string
+	for _, model := range models {
+		for _, label := range model.Labels {
+			if !seen[label] && strings.HasPrefix(label, toComplete) {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	return labels, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeInstances 从 serve 查询运行中的实例并补全其 ID（描述为模型路径），
+// 以及只有一个运行中实例的模型的名称和别名
+func completeInstances(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	instances, err := listRunningModels()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var ids []string
+	running := map[string]int{}
+	for _, instance := range instances {
+		id := strconv.Itoa(instance.ID)
+		if strings.HasPrefix(id, toComplete) {
+			ids = append(ids, id+"\t"+instance.Model)
+		}
+		if instance.Name != "" {
+			running[instance.Name]++
+		}
+	}
+
+	models, err := listModels()
+	if err != nil {
+		return ids, cobra.ShellCompDirectiveNoFileComp
+	}
+	for _, model := range models {
+		if running[model.Name] != 1 {
+			continue
+		}
+		id, _ := instanceOfModel(instances, model.Name)
+		for _, name := range append([]string{model.Name}, model.Aliases...) {
+			if strings.HasPrefix(name, toComplete) {
+				ids = append(ids, fmt.Sprintf("%s\tinstance %d", name, id))
+			}
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeDownloads 从 serve 查询未结束的下载并补全其 ID，描述为下载来源
+func completeDownloads(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	downloads, err := apiClient().ListDownloads(context.Background())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var ids []string
+	for _, d := range downloads {
+		if !d.Finished() && strings.HasPrefix(d.ID, toComplete) {
+			ids = append(ids, d.ID+"\t"+downloadSource(d))
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeStopArgs 补全 stop 命令的实例 ID 以及 serve
+func completeStopArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	ids, directive := completeInstances(cmd, args, toComplete)
+	if len(args) == 0 && strings.HasPrefix("serve", toComplete) {
+		ids = append(ids, "serve	stop the oneinfer service and all models")
+	}
+	return ids, directive
+}
+
+// completeAddArgs 补全 add 命令的平台参数
+func completeAddArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 1 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	platforms := append(append([]string{}, supportedPlatforms...), "local")
+	return platforms, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeHost 补全常用的监听地址
+func completeHost(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{"127.0.0.1\tlocal only", "0.0.0.0\tall interfaces"}, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePort 补全 8080 起未被运行中实例占用、且当前可以监听的端口
+func completePort(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	used := map[int]bool{}
+	if instances, err := listRunningModels(); err == nil {
+		for _, instance := range instances {
+			used[instance.Port] = true
+		}
+	}
+
+	var ports []string
+	for port := 8080; port < 8100 && len(ports) < 5; port++ {
+		if used[port] {
+			continue
+		}
+		listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			continue
+		}
+		listener.Close()
+		ports = append(ports, strconv.Itoa(port)+"\tfree")
+	}
+	return ports, cobra.ShellCompDirectiveNoFileComp
+}
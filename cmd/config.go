@@ -0,0 +1,498 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// oneinferConfig ~/.oneinfer/config.json，网络相关的设置对 Go 中的平台 API 请求、
+// Python 下载脚本和 pip 安装一致生效
+type oneinferConfig struct {
+	Proxy       proxyConfig       `json:"proxy"`
+	CABundle    string            `json:"ca_bundle,omitempty"`     // 额外信任的 CA 证书（PEM）
+	Mirrors     map[string]string `json:"mirrors,omitempty"`       // 各平台的镜像地址，如 https://hf-mirror.com
+	Tokens      map[string]string `json:"tokens,omitempty"`        // 各平台的访问令牌
+	PipIndexURL string            `json:"pip_index_url,omitempty"` // --install-deps 使用的 pip 源
+	Webhooks    []webhookConfig   `json:"webhooks,omitempty"`      // 由 webhook 命令管理
+	ImportRoots []string          `json:"import_roots,omitempty"`  // 允许通过 API 导入本地模型的目录
+}
+
+// proxyConfig 代理设置，未设置时沿用环境变量
+type proxyConfig struct {
+	HTTP    string `json:"http,omitempty"`
+	HTTPS   string `json:"https,omitempty"`
+	NoProxy string `json:"no_proxy,omitempty"`
+}
+
+// configKeys config 命令支持的键，mirrors 和 tokens 后接平台名
+var configKeys = []string{
+	"proxy.http", "proxy.https", "proxy.no_proxy", "ca_bundle", "pip_index_url",
+	"mirrors.huggingface", "mirrors.modelscope", "tokens.huggingface", "tokens.modelscope", "import_roots",
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show or change proxy, mirror, CA bundle and token settings used for downloads",
+	Long: `Show or change the settings in ~/.oneinfer/config.json. They apply to every hub request and download,
+including the Python download libraries and pip, regardless of the proxy variables in the environment.
+
+Keys: ` + strings.Join(configKeys, ", "),
+	Args: validArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		out := cfg.masked()
+		return printResult(out, func(w io.Writer) {
+			t := &table{headers: []string{"KEY", "VALUE"}}
+			for _, key := range configKeys {
+				if value := out.get(key); value != "" {
+					t.addRow([]string{key, value})
+				}
+			}
+			t.write(w)
+		})
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting",
+	Args:  validArgs(cobra.ExactArgs(2)),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return configKeys, cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := updateConfig(args[0], args[1]); err != nil {
+			return err
+		}
+		return printResult(map[string]string{"key": args[0], "status": "set"}, func(w io.Writer) {
+			fmt.Fprintf(w, "%s set.\n", args[0])
+		})
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a setting",
+	Args:  validArgs(cobra.ExactArgs(1)),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return configKeys, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := updateConfig(args[0], ""); err != nil {
+			return err
+		}
+		return printResult(map[string]string{"key": args[0], "status": "unset"}, func(w io.Writer) {
+			fmt.Fprintf(w, "%s unset.\n", args[0])
+		})
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// configPath 返回配置文件路径
+func configPath() (string, error) {
+	dir, err := oneinferDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// loadConfig 读取配置文件，文件不存在时返回空配置
+func loadConfig() (oneinferConfig, error) {
+	var cfg oneinferConfig
+	path, err := configPath()
+	if err != nil {
+		return cfg, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, newError(errValidation, "failed to parse %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// saveConfig 写入配置文件，包含令牌，只允许当前用户读取
+func saveConfig(cfg oneinferConfig) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// modifyConfig 持有 config.json.lock 读取、修改并保存配置，
+// 同时运行的 config set、webhook add 等命令不会覆盖彼此的修改
+func modifyConfig(modify func(cfg *oneinferConfig) error) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := modify(&cfg); err != nil {
+		return err
+	}
+	return saveConfig(cfg)
+}
+
+// updateConfig 校验并修改一个设置，value 为空时删除该设置
+func updateConfig(key, value string) error {
+	if !containsString(configKeys, key) {
+		return newError(errValidation, "unknown config key %q: expected one of %s", key, strings.Join(configKeys, ", "))
+	}
+	if value != "" {
+		if err := validateConfigValue(key, value); err != nil {
+			return err
+		}
+	}
+	return modifyConfig(func(cfg *oneinferConfig) error {
+		cfg.set(key, value)
+		return nil
+	})
+}
+
+// validateConfigValue 校验设置的值
+func validateConfigValue(key, value string) error {
+	switch {
+	case key == "proxy.http" || key == "proxy.https" || key == "pip_index_url" || strings.HasPrefix(key, "mirrors."):
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && !strings.HasPrefix(key, "proxy.")) {
+			return newError(errValidation, "invalid URL %q for %s", value, key)
+		}
+	case key == "ca_bundle":
+		if _, err := loadCABundle(value); err != nil {
+			return err
+		}
+	case key == "import_roots":
+		for _, root := range splitList(value) {
+			if info, err := os.Stat(root); err != nil || !info.IsDir() || !filepath.IsAbs(root) {
+				return newError(errValidation, "invalid import root %q: expected an absolute path of an existing directory", root)
+			}
+		}
+	}
+	return nil
+}
+
+// get 返回设置的值
+func (c oneinferConfig) get(key string) string {
+	switch key {
+	case "proxy.http":
+		return c.Proxy.HTTP
+	case "proxy.https":
+		return c.Proxy.HTTPS
+	case "proxy.no_proxy":
+		return c.Proxy.NoProxy
+	case "ca_bundle":
+		return c.CABundle
+	case "pip_index_url":
+		return c.PipIndexURL
+	case "import_roots":
+		return strings.Join(c.ImportRoots, ",")
+	}
+	if platform, ok := strings.CutPrefix(key, "mirrors."); ok {
+		return c.Mirrors[platform]
+	}
+	if platform, ok := strings.CutPrefix(key, "tokens."); ok {
+		return c.Tokens[platform]
+	}
+	return ""
+}
+
+// set 修改设置的值，value 为空时删除
+func (c *oneinferConfig) set(key, value string) {
+	switch key {
+	case "proxy.http":
+		c.Proxy.HTTP = value
+	case "proxy.https":
+		c.Proxy.HTTPS = value
+	case "proxy.no_proxy":
+		c.Proxy.NoProxy = value
+	case "ca_bundle":
+		c.CABundle = value
+	case "pip_index_url":
+		c.PipIndexURL = value
+	case "import_roots":
+		c.ImportRoots = nil
+		for _, root := range splitList(value) {
+			c.ImportRoots = append(c.ImportRoots, filepath.Clean(root))
+		}
+	}
+	if platform, ok := strings.CutPrefix(key, "mirrors."); ok {
+		c.Mirrors = setMapValue(c.Mirrors, platform, strings.TrimRight(value, "/"))
+	}
+	if platform, ok := strings.CutPrefix(key, "tokens."); ok {
+		c.Tokens = setMapValue(c.Tokens, platform, value)
+	}
+}
+
+// setMapValue 设置 map 中的值，value 为空时删除，map 为空时返回 nil
+func setMapValue(m map[string]string, key, value string) map[string]string {
+	if value == "" {
+		delete(m, key)
+	} else {
+		if m == nil {
+			m = map[string]string{}
+		}
+		m[key] = value
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// masked 返回隐藏令牌后的配置，用于输出
+func (c oneinferConfig) masked() oneinferConfig {
+	out := c
+	out.Tokens = nil
+	for platform, token := range c.Tokens {
+		masked := "****"
+		if len(token) > 8 {
+			masked = token[:4] + "****"
+		}
+		out.Tokens = setMapValue(out.Tokens, platform, masked)
+	}
+	out.Webhooks = nil
+	for _, hook := range c.Webhooks {
+		if hook.Secret != "" {
+			hook.Secret = "****"
+		}
+		out.Webhooks = append(out.Webhooks, hook)
+	}
+	return out
+}
+
+// loadCABundle 读取 PEM 证书，返回包含系统证书和这些证书的证书池
+func loadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, wrapError(errValidation, err, "cannot read CA bundle")
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, newError(errValidation, "no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// systemCAFiles 各发行版的系统 CA 证书文件，与 crypto/x509 在 Linux 上查找的位置一致
+var systemCAFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+// combinedCABundle 把系统 CA 证书和 ca_bundle 合并写入 ~/.oneinfer/ca-bundle.pem 并返回其路径。
+// requests、pip 等通过环境变量只能指定一个证书文件，直接指向 ca_bundle 会使系统证书失效，
+// 合并后与 Go 中的请求一样，在系统证书的基础上额外信任 ca_bundle。
+func (c oneinferConfig) combinedCABundle() (string, error) {
+	custom, err := os.ReadFile(c.CABundle)
+	if err != nil {
+		return "", wrapError(errValidation, err, "cannot read CA bundle")
+	}
+	dir, err := oneinferDir()
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(dir, "ca-bundle.pem")
+
+	// 环境中已指定证书文件时以它为系统证书
+	candidates := systemCAFiles
+	if file := os.Getenv("SSL_CERT_FILE"); file != "" && file != out {
+		candidates = append([]string{file}, candidates...)
+	}
+	var system []byte
+	for _, file := range candidates {
+		if system, err = os.ReadFile(file); err == nil {
+			break
+		}
+	}
+
+	var data []byte
+	if len(system) > 0 {
+		data = append(bytes.TrimRight(system, "\n"), '\n')
+	}
+	data = append(data, custom...)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, "ca-bundle-*.pem")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return out, os.Rename(tmp.Name(), out)
+}
+
+// proxyFunc 返回 HTTP 客户端使用的代理函数，配置了代理时不再读取环境变量
+func (c oneinferConfig) proxyFunc() func(*http.Request) (*url.URL, error) {
+	if c.Proxy.HTTP == "" && c.Proxy.HTTPS == "" {
+		return http.ProxyFromEnvironment
+	}
+	noProxy := strings.Split(c.Proxy.NoProxy, ",")
+	return func(req *http.Request) (*url.URL, error) {
+		host := req.URL.Hostname()
+		for _, suffix := range noProxy {
+			suffix = strings.TrimPrefix(strings.TrimSpace(suffix), ".")
+			if suffix != "" && (host == suffix || strings.HasSuffix(host, "."+suffix)) {
+				return nil, nil
+			}
+		}
+		proxy := c.Proxy.HTTPS
+		if req.URL.Scheme == "http" || proxy == "" {
+			proxy = c.Proxy.HTTP
+		}
+		if proxy == "" {
+			return nil, nil
+		}
+		return url.Parse(proxy)
+	}
+}
+
+// transport 根据代理和 CA 设置创建 HTTP Transport
+func (c oneinferConfig) transport() (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = c.proxyFunc()
+	if c.CABundle != "" {
+		pool, err := loadCABundle(c.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return t, nil
+}
+
+// downloadEnv 返回 Python 下载脚本和 pip 使用的环境变量。
+// 配置中的代理、镜像、CA 和 pip 源会覆盖继承的环境变量，避免大小写不同的同名变量互相冲突。
+func (c oneinferConfig) downloadEnv() ([]string, error) {
+	override := map[string]string{}
+	if c.Proxy.HTTP != "" || c.Proxy.HTTPS != "" {
+		for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "ALL_PROXY"} {
+			override[name] = ""
+		}
+		override["HTTP_PROXY"] = c.Proxy.HTTP
+		override["HTTPS_PROXY"] = c.Proxy.HTTPS
+		if c.Proxy.HTTPS == "" {
+			override["HTTPS_PROXY"] = c.Proxy.HTTP
+		}
+		override["NO_PROXY"] = c.Proxy.NoProxy
+	}
+	if c.CABundle != "" {
+		bundle, err := c.combinedCABundle()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range []string{"REQUESTS_CA_BUNDLE", "SSL_CERT_FILE", "CURL_CA_BUNDLE", "PIP_CERT"} {
+			override[name] = bundle
+		}
+	}
+	if c.PipIndexURL != "" {
+		override["PIP_INDEX_URL"] = c.PipIndexURL
+	}
+	if mirror := c.Mirrors["huggingface"]; mirror != "" {
+		override["HF_ENDPOINT"] = mirror
+	}
+	if mirror := c.Mirrors["modelscope"]; mirror != "" {
+		if u, err := url.Parse(mirror); err == nil {
+			override["MODELSCOPE_DOMAIN"] = u.Host
+			override["MODELSCOPE_URL_SCHEME"] = u.Scheme + "://"
+		}
+	}
+
+	env := []string{}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if _, ok := override[strings.ToUpper(name)]; ok {
+			continue
+		}
+		env = append(env, kv)
+	}
+	names := make([]string, 0, len(override))
+	for name := range override {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if override[name] == "" {
+			continue
+		}
+		env = append(env, name+"="+override[name])
+		// 代理变量同时设置小写形式，部分工具只读取小写
+		if strings.HasSuffix(name, "_PROXY") {
+			env = append(env, strings.ToLower(name)+"="+override[name])
+		}
+	}
+	return env, nil
+}
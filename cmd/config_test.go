@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testSystemPEM = "-----BEGIN CERTIFICATE-----\nU1lTVEVN\n-----END CERTIFICATE-----"
+	testCorpPEM   = "-----BEGIN CERTIFICATE-----\nQ09SUA==\n-----END CERTIFICATE-----\n"
+)
+
+// 下载脚本使用的证书文件应同时包含系统证书和 ca_bundle
+func TestDownloadEnvCombinesCABundle(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	system := filepath.Join(home, "system.pem")
+	corp := filepath.Join(home, "corp.pem")
+	os.WriteFile(system, []byte(testSystemPEM), 0644)
+	os.WriteFile(corp, []byte(testCorpPEM), 0644)
+	t.Setenv("SSL_CERT_FILE", system)
+	t.Setenv("requests_ca_bundle", "/somewhere/else.pem")
+
+	env, err := oneinferConfig{CABundle: corp}.downloadEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	combined := filepath.Join(home, ".oneinfer", "ca-bundle.pem")
+	vars := map[string]string{}
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		if _, dup := vars[strings.ToUpper(name)]; dup {
+			t.Errorf("%s is set more than once", name)
+		}
+		vars[strings.ToUpper(name)] = value
+	}
+	for _, name := range []string{"REQUESTS_CA_BUNDLE", "SSL_CERT_FILE", "CURL_CA_BUNDLE", "PIP_CERT"} {
+		if vars[name] != combined {
+			t.Errorf("%s = %q, want %q", name, vars[name], combined)
+		}
+	}
+
+	data, err := os.ReadFile(combined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := testSystemPEM + "\n" + testCorpPEM; string(data) != want {
+		t.Errorf("combined bundle:\n%s\nwant:\n%s", data, want)
+	}
+
+	// 再次生成时不能把上一次的合并结果当作系统证书
+	t.Setenv("SSL_CERT_FILE", combined)
+	if _, err := (oneinferConfig{CABundle: corp}).downloadEnv(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(combined)
+	if strings.Count(string(data), "Q09SUA==") != 1 {
+		t.Errorf("custom bundle included more than once:\n%s", data)
+	}
+}
+
+func TestDownloadEnvMissingCABundle(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, err := oneinferConfig{CABundle: "/nonexistent/ca.pem"}.downloadEnv()
+	if kindOf(err) != errValidation {
+		t.Errorf("error = %v, want a validation error", err)
+	}
+}
+
+// 并发修改配置时每次修改都应保留，且不留下临时文件
+func TestModifyConfigConcurrent(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- modifyConfig(func(cfg *oneinferConfig) error {
+				// 拉长读取和保存之间的间隔，没有锁时修改必然互相覆盖
+				time.Sleep(5 * time.Millisecond)
+				if cfg.Tokens == nil {
+					cfg.Tokens = map[string]string{}
+				}
+				cfg.Tokens[fmt.Sprint(i)] = "token"
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Tokens) != 20 {
+		t.Errorf("got %d tokens, want 20", len(cfg.Tokens))
+	}
+	path, _ := configPath()
+	if info, err := os.Stat(path); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("config.json mode = %v, want 0600", info.Mode().Perm())
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	if len(leftovers) != 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 固定的下载脚本，参数通过 stdin 以 JSON 形式传入
+//
+//go:embed scripts/download.py
+var downloadScript string
+
+// 支持的远程平台
+var supportedPlatforms = []string{"huggingface", "modelscope"}
+
+var (
+	// repo ID 形如 <owner>/<name>，只允许字母、数字以及 . _ -
+	repoIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,95}/[A-Za-z0-9][A-Za-z0-9_.-]{0,95}$`)
+	// 文件模式只允许路径字符和 glob 通配符
+	filePatternPattern = regexp.MustCompile(`^[A-Za-z0-9_.*?\[\]/-]{1,255}$`)
+)
+
+// downloadParams 传给下载脚本的参数
+type downloadParams struct {
+	Platform    string `json:"platform"`
+	RepoID      string `json:"repo_id"`
+	Revision    string `json:"revision"`
+	DestPath    string `json:"dest_path"`
+	FilePattern string `json:"file_pattern"`
+	InstallDeps bool   `json:"install_deps"`
+	Token       string `json:"token,omitempty"` // 访问令牌，不通过命令行参数或环境变量传递
+}
+
+// isSupportedPlatform 判断是否为支持的远程平台
+func isSupportedPlatform(platform string) bool {
+	for _, p := range supportedPlatforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRepoID 校验远程仓库 ID
+func validateRepoID(repoID string) error {
+	if !repoIDPattern.MatchString(repoID) || strings.Contains(repoID, "..") || strings.Contains(repoID, "--") {
+		return newError(errValidation, "invalid repository id %q: expected <owner>/<name> using letters, digits, '.', '_' or '-'", repoID)
+	}
+	return nil
+}
+
+// validateFilePattern 校验文件模式，空字符串表示下载整个仓库
+func validateFilePattern(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	if !filePatternPattern.MatchString(pattern) || strings.HasPrefix(pattern, "/") {
+		return newError(errValidation, "invalid file pattern %q: only letters, digits, '.', '_', '-', '/' and glob characters are allowed", pattern)
+	}
+	for _, part := range strings.Split(pattern, "/") {
+		if part == ".." {
+			return newError(errValidation, "invalid file pattern %q: '..' is not allowed", pattern)
+		}
+	}
+	return nil
+}
+
+// validateDownload 校验下载参数
+func validateDownload(platform, repoID, filePattern string) error {
+	if !isSupportedPlatform(platform) {
+		return newError(errValidation, "unsupported platform %q: expected one of %s or local", platform, strings.Join(supportedPlatforms, ", "))
+	}
+	if err := validateRepoID(repoID); err != nil {
+		return err
+	}
+	return validateFilePattern(filePattern)
+}
+
+// downloadModelWithPython 使用内嵌的 Python 脚本下载模型，输出到终端，进度上报给 serve
+func downloadModelWithPython(platform, modelName, revision, destPath, filePattern string, installDeps bool) error {
+	return runDownloadScript(context.Background(), platform, modelName, revision, destPath, filePattern, addOptions{InstallDeps: installDeps})
+}
+
+// runDownloadScript 运行下载脚本，ctx 取消时结束 Python 进程。
+// opts.Progress 接收下载事件，为空时上报给 serve；opts.Output 接收脚本输出，为空时输出到终端。
+func runDownloadScript(ctx context.Context, platform, modelName, revision, destPath, filePattern string, opts addOptions) error {
+	if err := validateDownload(platform, modelName, filePattern); err != nil {
+		return err
+	}
+	if revision != "" {
+		if err := validateRevision(revision); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	params, err := json.Marshal(downloadParams{
+		Platform:    platform,
+		RepoID:      modelName,
+		Revision:    revision,
+		DestPath:    destPath,
+		FilePattern: filePattern,
+		InstallDeps: opts.InstallDeps,
+		Token:       cfg.Tokens[platform],
+	})
+	if err != nil {
+		return err
+	}
+
+	// 脚本内容固定，参数只通过 stdin 传入；代理、镜像和 CA 按配置显式设置到环境变量中
+	cmd := exec.CommandContext(ctx, "python3", "-c", downloadScript)
+	cmd.Stdin = strings.NewReader(string(params))
+	if cmd.Env, err = cfg.downloadEnv(); err != nil {
+		return err
+	}
+
+	// 设置输出管道
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("error creating stdout pipe: %v", err)
+	}
+
+	// 设置错误输出管道
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("error creating stderr pipe: %v", err)
+	}
+
+	// 启动命令
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting command: %v", err)
+	}
+
+	// 上报下载进度，进度为暂存目录中已写入的字节数
+	report := opts.Progress
+	if report == nil {
+		report = notifyDownload
+	}
+	stdoutLog, stderrLog := infoOut(), io.Writer(os.Stderr)
+	if opts.Output != nil {
+		stdoutLog, stderrLog = opts.Output, opts.Output
+	}
+	event := downloadEvent{Platform: platform, Repo: modelName, Revision: revision, Files: filePattern}
+	report(eventDownloadStarted, event)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(downloadProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				progress := event
+				progress.Bytes = dirSize(destPath)
+				report(eventDownloadProgress, progress)
+			}
+		}
+	}()
+
+	// 实时读取标准输出和标准错误输出，等两者读完再等待进程退出
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			fmt.Fprintln(stdoutLog, scanner.Text()) // 打印 Python 脚本的输出，包含进度信息
+		}
+		if err := scanner.Err(); err != nil {
+			warnf(opts.Logger, "error reading download output: %v", err)
+		}
+	}()
+
+	var lastError string
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			fmt.Fprintln(stderrLog, scanner.Text()) // 打印 Python 脚本的错误输出
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				lastError = line
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			warnf(opts.Logger, "error reading download error output: %v", err)
+		}
+	}()
+
+	// 等待命令完成
+	wg.Wait()
+	err = cmd.Wait()
+	close(done)
+	event.Bytes = dirSize(destPath)
+	if err != nil {
+		event.Error = err.Error()
+		report(eventDownloadFailed, event)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// 输出没有显示在终端时，错误中带上脚本的最后一行错误输出
+		if opts.Output != nil && lastError != "" {
+			return fmt.Errorf("error downloading model using Python: %v: %s", err, lastError)
+		}
+		return fmt.Errorf("error downloading model using Python: %v", err)
+	}
+	report(eventDownloadCompleted, event)
+
+	return nil
+}
+
+// dirSize 返回目录中所有文件的总大小，读取出错的文件忽略
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
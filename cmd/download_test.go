@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+// checkValidation 校验 validate 对每个输入的结果：valid 为 true 时应通过，否则应返回校验错误
+func checkValidation(t *testing.T, validate func(string) error, tests []struct {
+	in    string
+	valid bool
+}) {
+	t.Helper()
+	for _, tt := range tests {
+		err := validate(tt.in)
+		switch {
+		case tt.valid && err != nil:
+			t.Errorf("%q: unexpected error: %v", tt.in, err)
+		case !tt.valid && err == nil:
+			t.Errorf("%q: expected an error", tt.in)
+		case !tt.valid && kindOf(err) != errValidation:
+			t.Errorf("%q: error kind = %s, want %s", tt.in, kindOf(err), errValidation)
+		}
+	}
+}
+
+func TestValidateRepoID(t *testing.T) {
+	checkValidation(t, validateRepoID, []struct {
+		in    string
+		valid bool
+	}{
+		{"unsloth/Qwen3-8B-GGUF", true},
+		{"Qwen/Qwen2.5-0.5B-Instruct", true},
+		{"a_b/c.d", true},
+		{"", false},
+		{"qwen", false},
+		{"a/b/c", false},
+		{"../etc", false},
+		{"a/..", false},
+		{"a/b..c", false},
+		{"./a", false},
+		{"/etc/passwd", false},
+		{"a//b", false},
+		{"-rf/x", false},
+		{"a/-x", false},
+		{"--upload-pack/x", false},
+		{"a/b--c", false},
+		{"a/b\x00", false},
+		{"a/b\nc", false},
+		{"a/b\n", false},
+		{"a/b;rm -rf ~", false},
+		{"a/b$(id)", false},
+		{"a/`id`", false},
+		{"a/b|c", false},
+		{"a/b&c", false},
+		{"a/b c", false},
+		{"a/b'c", false},
+		{"a/b\"c", false},
+		{"a\\b/c", false},
+		{"a/b@main", false},
+		{strings.Repeat("a", 96) + "/b", true},
+		{strings.Repeat("a", 97) + "/b", false},
+		{"a/" + strings.Repeat("b", 97), false},
+	})
+}
+
+func TestValidateFilePattern(t *testing.T) {
+	checkValidation(t, validateFilePattern, []struct {
+		in    string
+		valid bool
+	}{
+		{"", true},
+		{"*.gguf", true},
+		{"*Q4_K_M*.gguf", true},
+		{"model-0000[1-3]-of-00003.gguf", true},
+		{"sub/dir/*.gguf", true},
+		{"a..b.gguf", true},
+		{"/etc/passwd", false},
+		{"..", false},
+		{"../*.gguf", false},
+		{"sub/../../x", false},
+		{"sub/..", false},
+		{"*.gguf\x00", false},
+		{"*.gguf\n*", false},
+		{"*.gguf;id", false},
+		{"$(id)", false},
+		{"*.gguf | sh", false},
+		{"a\\b", false},
+		{"~/x", false},
+		{strings.Repeat("a", 255), true},
+		{strings.Repeat("a", 256), false},
+	})
+}
+
+func TestValidateRevision(t *testing.T) {
+	checkValidation(t, validateRevision, []struct {
+		in    string
+		valid bool
+	}{
+		{"main", true},
+		{"v1.0", true},
+		{"refs/pr/1", true},
+		{"0123456789abcdef0123456789abcdef01234567", true},
+		{"", false},
+		{"--upload-pack=touch /tmp/x", false},
+		{"-b", false},
+		{"refs/../", false},
+		{"refs/../../etc", false},
+		{"..", false},
+		{"/main", false},
+		{"main\x00", false},
+		{"main\n", false},
+		{"main;id", false},
+		{"main@{1}", false},
+		{"main branch", false},
+		{strings.Repeat("a", 128), true},
+		{strings.Repeat("a", 129), false},
+	})
+}
+
+func TestValidateDownload(t *testing.T) {
+	if err := validateDownload("huggingface", "unsloth/Qwen3-8B-GGUF", "*.gguf"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, platform := range []string{"", "local", "github", "huggingface "} {
+		if err := validateDownload(platform, "a/b", ""); kindOf(err) != errValidation {
+			t.Errorf("platform %q: error = %v, want a validation error", platform, err)
+		}
+	}
+}
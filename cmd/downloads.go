@@ -0,0 +1,834 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"oneinfer/client"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/cobra"
+)
+
+// 下载状态
+const (
+	downloadQueued    = "queued"
+	downloadRunning   = "downloading"
+	downloadCompleted = "completed"
+	downloadFailed    = "failed"
+	downloadCanceled  = "canceled"
+)
+
+const (
+	// downloadRetention 结束的下载保留在列表中的时间
+	downloadRetention = 24 * time.Hour
+	// defaultMaxDownloads serve 默认同时执行的下载任务数
+	defaultMaxDownloads = 2
+	// downloadShutdownTimeout serve 退出时等待下载进程结束的时间
+	downloadShutdownTimeout = 5 * time.Second
+	// errDownloadInterrupted serve 意外终止时正在执行的任务在下次启动时记录的错误
+	errDownloadInterrupted = "interrupted: oneinfer serve exited unexpectedly during the download, add the model again to retry"
+)
+
+// downloadJob 一个下载：serve 在后台执行的下载或本地导入任务，或 CLI 前台下载时上报的下载。
+// 本地导入任务的 platform 为 local，repo 为服务器上的路径。
+type downloadJob struct {
+	downloadEvent
+	Quant      string     `json:"quant,omitempty"`
+	FitMemory  bool       `json:"fit_memory,omitempty"`
+	Name       string     `json:"name,omitempty"` // 本地模型的名称
+	Mode       string     `json:"mode,omitempty"` // 本地模型的导入方式
+	Status     string     `json:"status"`
+	Model      string     `json:"model,omitempty"`      // 完成后注册的模型名称
+	Foreground bool       `json:"foreground,omitempty"` // 由 CLI 前台执行，serve 不能取消或续传
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	cancel context.CancelFunc // 取消排队或下载中的任务
+}
+
+var (
+	downloadJobs = make(map[string]*downloadJob)
+	// foregroundDownloads CLI 前台下载的 platform:repo@revision:files 到下载 ID 的映射
+	foregroundDownloads = make(map[string]string)
+	downloadMux         sync.Mutex
+	// downloadSlots 限制同时执行的下载任务数
+	downloadSlots chan struct{}
+	// downloadWorkers 用于 serve 退出时等待任务结束
+	downloadWorkers sync.WaitGroup
+	// suspending serve 退出时为 true，此时中断的任务保持排队状态，下次启动时续传
+	suspending bool
+)
+
+// finished 判断下载是否已结束
+func (j *downloadJob) finished() bool {
+	return j.Status == downloadCompleted || j.Status == downloadFailed || j.Status == downloadCanceled
+}
+
+// request 返回任务对应的添加请求，用于 library.* 事件
+func (j *downloadJob) request() libraryAddRequest {
+	if j.Platform == "local" {
+		return libraryAddRequest{Platform: j.Platform, Name: j.Name, Path: j.Repo, Mode: j.Mode}
+	}
+	repo := j.Repo
+	if j.Revision != "" {
+		repo += "@" + j.Revision
+	}
+	return libraryAddRequest{Platform: j.Platform, Repo: repo, FilePattern: j.Files, Quant: j.Quant, FitMemory: j.FitMemory}
+}
+
+// downloadsPath 返回 ~/.oneinfer/downloads.json，保存后台下载任务
+func downloadsPath() (string, error) {
+	dir, err := oneinferDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "downloads.json"), nil
+}
+
+// downloadStagingDir 返回任务的暂存目录，serve 重启后在同一目录中续传
+func downloadStagingDir(id string) (string, error) {
+	dir, err := oneinferDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tmp", "job-"+id), nil
+}
+
+// saveDownloadJobs 保存后台下载任务，持有 downloads.json.lock 先写临时文件再重命名，
+// 共用 ~/.oneinfer 的多个 serve 进程不会交错写入。调用方需持有 downloadMux。
+func saveDownloadJobs() {
+	path, err := downloadsPath()
+	if err != nil {
+		slog.Warn("failed to save download jobs", "error", err)
+		return
+	}
+	jobs := []*downloadJob{}
+	for _, job := range downloadJobs {
+		if !job.Foreground {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err == nil {
+		var unlock func()
+		if unlock, err = lockFile(path + ".lock"); err == nil {
+			err = writeFileAtomic(path, data, 0644)
+			unlock()
+		}
+	}
+	if err != nil {
+		slog.Warn("failed to save download jobs", "path", path, "error", err)
+	}
+}
+
+// startDownloadJobs 初始化下载任务管理，重新排队上次 serve 退出时未完成的任务。
+// 正常退出时中断的任务已改回排队状态；仍处于下载中的任务说明 serve 在下载或导入时意外终止，
+// 暂存目录和注册表可能不完整，这些任务标记为失败而不是自动续传。
+func startDownloadJobs(maxDownloads int) error {
+	downloadSlots = make(chan struct{}, maxDownloads)
+
+	path, err := downloadsPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	var jobs []*downloadJob
+	if err == nil {
+		err = json.Unmarshal(data, &jobs)
+	}
+	if err != nil {
+		slog.Warn("failed to load download jobs", "path", path, "error", err)
+		return nil
+	}
+
+	downloadMux.Lock()
+	defer downloadMux.Unlock()
+	now := time.Now().UTC()
+	for _, job := range jobs {
+		if job.finished() {
+			if now.Sub(job.UpdatedAt) <= downloadRetention {
+				downloadJobs[job.ID] = job
+			}
+			continue
+		}
+		if job.Status == downloadRunning {
+			slog.Warn("download was interrupted by an unexpected exit, marking it failed", "id", job.ID, "platform", job.Platform, "repo", job.Repo, "files", job.Files)
+			job.Status = downloadFailed
+			job.Error = errDownloadInterrupted
+			finished := now
+			job.UpdatedAt, job.FinishedAt = now, &finished
+			downloadJobs[job.ID] = job
+			if staging, err := downloadStagingDir(job.ID); err == nil {
+				os.RemoveAll(staging)
+			}
+			publishEvent(eventDownloadFailed, *job)
+			continue
+		}
+		slog.Info("resuming download", "id", job.ID, "platform", job.Platform, "repo", job.Repo, "files", job.Files)
+		job.Status = downloadQueued
+		job.UpdatedAt = now
+		downloadJobs[job.ID] = job
+		enqueueDownloadJob(job)
+	}
+	saveDownloadJobs()
+	return nil
+}
+
+// suspendDownloadJobs 在 serve 退出前中断所有任务并等待下载进程结束，任务在下次启动时续传
+func suspendDownloadJobs() {
+	downloadMux.Lock()
+	suspending = true
+	for _, job := range downloadJobs {
+		if job.cancel != nil {
+			job.cancel()
+		}
+	}
+	downloadMux.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		downloadWorkers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(downloadShutdownTimeout):
+		slog.Warn("downloads did not stop in time")
+	}
+}
+
+// newDownloadID 生成不重复的下载 ID。调用方需持有 downloadMux。
+func newDownloadID() string {
+	for {
+		id := newRequestID()[:8]
+		if _, ok := downloadJobs[id]; !ok {
+			return id
+		}
+	}
+}
+
+// pruneDownloadJobs 删除结束较久的下载。调用方需持有 downloadMux。
+func pruneDownloadJobs(now time.Time) {
+	for id, job := range downloadJobs {
+		if job.finished() && now.Sub(job.UpdatedAt) > downloadRetention {
+			delete(downloadJobs, id)
+		}
+	}
+}
+
+// createDownloadJob 校验请求并创建后台下载任务，任务排队等待空闲的下载名额
+func createDownloadJob(req libraryAddRequest) (downloadJob, error) {
+	if req.Platform == "local" {
+		return downloadJob{}, newError(errValidation, "local models are imported with POST %s/models", apiPrefix)
+	}
+	if req.Name != "" || req.Path != "" || req.Mode != "" {
+		return downloadJob{}, newError(errValidation, "name, path and mode are only supported for local models")
+	}
+	repoID, revision := splitRevision(req.Repo)
+	if err := validateDownload(req.Platform, repoID, req.FilePattern); err != nil {
+		return downloadJob{}, err
+	}
+	if revision != "" {
+		if err := validateRevision(revision); err != nil {
+			return downloadJob{}, err
+		}
+	}
+	if req.FilePattern != "" && (req.Quant != "" || req.FitMemory) {
+		return downloadJob{}, newError(errValidation, "specify either a file pattern or quant/fit_memory, not both")
+	}
+
+	downloadMux.Lock()
+	for _, job := range downloadJobs {
+		if !job.finished() && !job.Foreground && job.Platform == req.Platform && job.Repo == repoID &&
+			job.Revision == revision && job.Files == req.FilePattern && job.Quant == req.Quant && job.FitMemory == req.FitMemory {
+			downloadMux.Unlock()
+			return downloadJob{}, newError(errConflict, "%s is already being downloaded (download %s)", req.Repo, job.ID)
+		}
+	}
+	snapshot := queueDownloadJob(&downloadJob{
+		downloadEvent: downloadEvent{Platform: req.Platform, Repo: repoID, Revision: revision, Files: req.FilePattern},
+		Quant:         req.Quant,
+		FitMemory:     req.FitMemory,
+	})
+	downloadMux.Unlock()
+
+	slog.Info("download queued", "id", snapshot.ID, "platform", snapshot.Platform, "repo", req.Repo, "files", snapshot.Files)
+	return snapshot, nil
+}
+
+// createImportJob 校验请求并创建导入本地模型的后台任务，与下载共用任务列表和名额。
+// 路径必须位于 import_roots 中。
+func createImportJob(req libraryAddRequest) (downloadJob, error) {
+	if req.Mode == "" {
+		req.Mode = importCopy
+	}
+	if req.Repo != "" || req.FilePattern != "" || req.Quant != "" || req.FitMemory {
+		return downloadJob{}, newError(errValidation, "repo, file_pattern, quant and fit_memory are only supported for Hugging Face and ModelScope")
+	}
+	if err := validateLocalName(req.Name); err != nil {
+		return downloadJob{}, err
+	}
+	if err := validateImportMode(req.Mode); err != nil {
+		return downloadJob{}, err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return downloadJob{}, err
+	}
+	path, err := resolveImportPath(cfg.ImportRoots, req.Path)
+	if err != nil {
+		return downloadJob{}, err
+	}
+	if err := ensureNameAvailable(req.Name); err != nil {
+		return downloadJob{}, err
+	}
+
+	downloadMux.Lock()
+	for _, job := range downloadJobs {
+		if !job.finished() && job.Platform == "local" && job.Name == req.Name {
+			downloadMux.Unlock()
+			return downloadJob{}, newError(errConflict, "model '%s' is already being imported (download %s)", req.Name, job.ID)
+		}
+	}
+	snapshot := queueDownloadJob(&downloadJob{
+		downloadEvent: downloadEvent{Platform: "local", Repo: path},
+		Name:          req.Name,
+		Mode:          req.Mode,
+	})
+	downloadMux.Unlock()
+
+	slog.Info("import queued", "id", snapshot.ID, "name", snapshot.Name, "path", path, "mode", snapshot.Mode)
+	return snapshot, nil
+}
+
+// queueDownloadJob 分配 ID，保存任务并在后台排队执行，返回任务快照。调用方需持有 downloadMux。
+func queueDownloadJob(job *downloadJob) downloadJob {
+	now := time.Now().UTC()
+	job.ID = newDownloadID()
+	job.Status = downloadQueued
+	job.CreatedAt, job.UpdatedAt = now, now
+	downloadJobs[job.ID] = job
+	pruneDownloadJobs(now)
+	saveDownloadJobs()
+	enqueueDownloadJob(job)
+	snapshot := *job
+	// 持有锁时发布，保证同一任务的事件按顺序发出
+	publishEvent(eventDownloadQueued, snapshot)
+	return snapshot
+}
+
+// enqueueDownloadJob 在后台执行任务。调用方需持有 downloadMux。
+func enqueueDownloadJob(job *downloadJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job.cancel = cancel
+	downloadWorkers.Add(1)
+	go runDownloadJob(ctx, job)
+}
+
+// runDownloadJob 等待空闲的下载名额，然后下载并注册模型
+func runDownloadJob(ctx context.Context, job *downloadJob) {
+	defer downloadWorkers.Done()
+	select {
+	case downloadSlots <- struct{}{}:
+		defer func() { <-downloadSlots }()
+	case <-ctx.Done():
+		finishDownloadJob(job, modelInfo{}, ctx.Err())
+		return
+	}
+
+	started := updateDownloadJob(job, eventDownloadStarted, func(j *downloadJob) { j.Status = downloadRunning })
+	slog.Info("download started", "id", started.ID, "platform", started.Platform, "repo", started.Repo, "files", started.Files)
+	// 任务中的提示和警告写入日志，不输出到 serve 的终端
+	log := slog.With("job", started.ID)
+	if started.Platform == "local" {
+		model, err := runImportJob(started, log)
+		finishDownloadJob(job, model, err)
+		return
+	}
+	staging, err := downloadStagingDir(started.ID)
+	if err != nil {
+		finishDownloadJob(job, modelInfo{}, err)
+		return
+	}
+	req := started.request()
+	opts := addOptions{
+		Quant:      req.Quant,
+		FitMemory:  req.FitMemory,
+		StagingDir: staging,
+		Progress: func(eventType string, data downloadEvent) {
+			if eventType == eventDownloadProgress || eventType == eventDownloadCompleted {
+				updateDownloadJob(job, eventDownloadProgress, func(j *downloadJob) { j.Bytes = data.Bytes })
+			}
+		},
+		Output: downloadOutput{log: log},
+		Logger: log,
+	}
+	model, err := addModel(ctx, req.Repo, req.Platform, req.FilePattern, opts)
+	finishDownloadJob(job, model, err)
+}
+
+// runImportJob 导入本地模型。任务可能是上次 serve 运行时创建的，导入前按当前的 import_roots 重新检查路径。
+func runImportJob(job downloadJob, log *slog.Logger) (modelInfo, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return modelInfo{}, err
+	}
+	path, err := resolveImportPath(cfg.ImportRoots, job.Repo)
+	if err != nil {
+		return modelInfo{}, err
+	}
+	return importLocalModel(job.Name, path, job.Mode, log)
+}
+
+// updateDownloadJob 修改任务并发布事件，状态变化时保存任务列表，返回修改后的任务
+func updateDownloadJob(job *downloadJob, eventType string, fn func(j *downloadJob)) downloadJob {
+	downloadMux.Lock()
+	fn(job)
+	job.UpdatedAt = time.Now().UTC()
+	snapshot := *job
+	if eventType != eventDownloadProgress {
+		saveDownloadJobs()
+	}
+	publishEvent(eventType, snapshot)
+	downloadMux.Unlock()
+	return snapshot
+}
+
+// finishDownloadJob 记录任务结果，删除暂存目录并通知模型库页面。
+// serve 退出时中断的任务保持排队状态和暂存目录，下次启动时续传。
+func finishDownloadJob(job *downloadJob, model modelInfo, err error) {
+	downloadMux.Lock()
+	if err != nil && suspending {
+		job.Status = downloadQueued
+		job.cancel = nil
+		saveDownloadJobs()
+		downloadMux.Unlock()
+		return
+	}
+	downloadMux.Unlock()
+
+	eventType := eventDownloadCompleted
+	switch {
+	case errors.Is(err, context.Canceled):
+		eventType = eventDownloadCanceled
+	case err != nil:
+		eventType = eventDownloadFailed
+	}
+	done := updateDownloadJob(job, eventType, func(j *downloadJob) {
+		now := time.Now().UTC()
+		j.FinishedAt = &now
+		j.cancel = nil
+		switch eventType {
+		case eventDownloadCompleted:
+			j.Status = downloadCompleted
+			j.Model = model.Name
+		case eventDownloadCanceled:
+			j.Status = downloadCanceled
+		default:
+			j.Status = downloadFailed
+			j.Error = err.Error()
+		}
+	})
+	if staging, err := downloadStagingDir(done.ID); err == nil {
+		os.RemoveAll(staging)
+	}
+
+	switch eventType {
+	case eventDownloadCompleted:
+		slog.Info("model added", "id", done.ID, "name", model.Name, "repo", done.Repo, "platform", done.Platform)
+		publishEvent(eventLibraryAdded, libraryAddEvent{Request: done.request(), Model: &model})
+	case eventDownloadCanceled:
+		slog.Info("download canceled", "id", done.ID, "repo", done.Repo, "platform", done.Platform)
+	default:
+		slog.Error("failed to add model", "id", done.ID, "repo", done.Repo, "platform", done.Platform, "error", err)
+		publishEvent(eventLibraryAddFailed, libraryAddEvent{Request: done.request(), Error: err.Error()})
+	}
+}
+
+// cancelDownloadJob 取消排队或下载中的任务，结果通过 download.canceled 事件通知
+func cancelDownloadJob(id string) (downloadJob, error) {
+	downloadMux.Lock()
+	defer downloadMux.Unlock()
+	job, ok := downloadJobs[id]
+	if !ok {
+		return downloadJob{}, newError(errNotFound, "download '%s' not found", id)
+	}
+	if job.Foreground {
+		return downloadJob{}, newError(errConflict, "download '%s' runs in the foreground of 'oneinfer add' and can only be stopped there", id)
+	}
+	if job.finished() || job.cancel == nil {
+		return downloadJob{}, newError(errConflict, "download '%s' is already %s", id, job.Status)
+	}
+	// 本地文件的导入开始后不能中断
+	if job.Platform == "local" && job.Status == downloadRunning {
+		return downloadJob{}, newError(errConflict, "download '%s' is already importing files and cannot be canceled", id)
+	}
+	job.cancel()
+	return *job, nil
+}
+
+// recordDownload 根据 CLI 前台下载上报的 download.* 事件更新下载列表，返回更新后的下载
+func recordDownload(eventType string, data downloadEvent) downloadJob {
+	key := data.Platform + ":" + data.Repo + "@" + data.Revision + ":" + data.Files
+	now := time.Now().UTC()
+
+	downloadMux.Lock()
+	defer downloadMux.Unlock()
+	id := foregroundDownloads[key]
+	job, ok := downloadJobs[id]
+	if !ok || eventType == eventDownloadStarted {
+		id = newDownloadID()
+		job = &downloadJob{Foreground: true, CreatedAt: now}
+		downloadJobs[id] = job
+		foregroundDownloads[key] = id
+	}
+	data.ID = id
+	job.downloadEvent = data
+	job.UpdatedAt = now
+	switch eventType {
+	case eventDownloadCompleted:
+		job.Status = downloadCompleted
+	case eventDownloadFailed:
+		job.Status = downloadFailed
+	default:
+		job.Status = downloadRunning
+	}
+	if job.finished() {
+		job.FinishedAt = &now
+		delete(foregroundDownloads, key)
+	}
+	pruneDownloadJobs(now)
+	return *job
+}
+
+// downloadOutput 把下载脚本的输出写入 debug 日志
+type downloadOutput struct {
+	log *slog.Logger
+}
+
+func (o downloadOutput) Write(p []byte) (int, error) {
+	o.log.Debug("download output", "line", strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// listDownloadsHandler 列出排队、进行中和最近结束的下载，按创建时间排序
+func listDownloadsHandler(w http.ResponseWriter, r *http.Request) {
+	downloadMux.Lock()
+	pruneDownloadJobs(time.Now().UTC())
+	list := make([]downloadJob, 0, len(downloadJobs))
+	for _, job := range downloadJobs {
+		list = append(list, *job)
+	}
+	downloadMux.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	requestLogger(r).Debug("listing downloads", "count", len(list))
+	writeJSON(w, http.StatusOK, list)
+}
+
+// createDownloadHandler 创建后台下载任务，返回 202 和任务地址
+func createDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	var req libraryAddRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	job, err := createDownloadJob(req)
+	if err != nil {
+		requestLogger(r).Warn("failed to create download", "platform", req.Platform, "repo", req.Repo, "error", err)
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	w.Header().Set("Location", apiPrefix+"/downloads/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// getDownloadHandler 返回一个下载的进度
+func getDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	downloadMux.Lock()
+	job, ok := downloadJobs[id]
+	var snapshot downloadJob
+	if ok {
+		snapshot = *job
+	}
+	downloadMux.Unlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("download '%s' not found", id), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// cancelDownloadHandler 取消下载任务，返回 202，任务结束时发布 download.canceled 事件
+func cancelDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := cancelDownloadJob(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	requestLogger(r).Info("canceling download", "id", job.ID, "repo", job.Repo)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// downloads 命令
+var downloadsCmd = &cobra.Command{
+	Use:   "downloads [download_id]",
+	Short: "List and follow downloads managed by oneinfer serve",
+	Long: `List the downloads of oneinfer serve: jobs queued with 'oneinfer add --async' or from the
+web UI, and foreground 'oneinfer add' downloads. Finished downloads are listed for 24 hours.
+
+With --follow, progress is printed until the given download (or every unfinished download)
+has finished. Following a download that fails or is canceled exits with an error.`,
+	Args:              validArgs(cobra.MaximumNArgs(1)),
+	ValidArgsFunction: completeDownloads,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		follow, _ := cmd.Flags().GetBool("follow")
+		var id string
+		if len(args) > 0 {
+			id = args[0]
+		}
+		if follow {
+			return followDownloads(id, os.Stdout)
+		}
+
+		ctx := context.Background()
+		if id != "" {
+			download, err := apiClient().GetDownload(ctx, id)
+			if err != nil {
+				return clientError(err)
+			}
+			return printResult(download, func(w io.Writer) {
+				writeDownloadTable(w, []client.Download{*download})
+			})
+		}
+		downloads, err := apiClient().ListDownloads(ctx)
+		if err != nil {
+			return clientError(err)
+		}
+		if downloads == nil {
+			downloads = []client.Download{}
+		}
+		return printResult(downloadListOutput{Downloads: downloads}, func(w io.Writer) {
+			if len(downloads) == 0 {
+				io.WriteString(w, "No downloads found.\n")
+				return
+			}
+			writeDownloadTable(w, downloads)
+		})
+	},
+}
+
+// downloadsCancelCmd 取消下载任务
+var downloadsCancelCmd = &cobra.Command{
+	Use:               "cancel <download_id>",
+	Short:             "Cancel a queued or running download",
+	Args:              validArgs(cobra.ExactArgs(1)),
+	ValidArgsFunction: completeDownloads,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		download, err := apiClient().CancelDownload(context.Background(), args[0])
+		if err != nil {
+			return clientError(err)
+		}
+		return printResult(download, func(w io.Writer) {
+			fmt.Fprintf(w, "Canceling download %s of %s\n", download.ID, download.Repo)
+		})
+	},
+}
+
+// downloadListOutput downloads 命令的输出结构
+type downloadListOutput struct {
+	Downloads []client.Download `json:"downloads"`
+}
+
+func init() {
+	downloadsCmd.Flags().BoolP("follow", "f", false, "Print progress until the download(s) finish")
+	downloadsCmd.AddCommand(downloadsCancelCmd)
+	rootCmd.AddCommand(downloadsCmd)
+}
+
+// downloadSource 返回 platform:repo@revision 形式的下载来源
+func downloadSource(d client.Download) string {
+	s := d.Platform + ":" + d.Repo
+	if d.Revision != "" {
+		s += "@" + d.Revision
+	}
+	return s
+}
+
+// downloadFiles 返回下载的文件模式或选择方式，本地导入返回导入方式
+func downloadFiles(d client.Download) string {
+	switch {
+	case d.Platform == "local":
+		return d.Mode
+	case d.Files != "":
+		return d.Files
+	case d.Quant != "":
+		return "quant " + d.Quant
+	case d.FitMemory:
+		return "fit memory"
+	}
+	return "all"
+}
+
+// writeDownloadTable 以表格输出下载列表
+func writeDownloadTable(w io.Writer, downloads []client.Download) {
+	t := &table{
+		headers:     []string{"ID", "SOURCE", "FILES", "STATUS", "SIZE", "MODEL"},
+		wideHeaders: []string{"CREATED", "ERROR"},
+	}
+	for _, d := range downloads {
+		status := d.Status
+		if d.Foreground {
+			status += " (foreground)"
+		}
+		model := d.Model
+		if model == "" {
+			model = "-"
+		}
+		t.addRow(
+			[]string{d.ID, downloadSource(d), downloadFiles(d), status, formatBytes(d.Bytes), model},
+			d.CreatedAt.Local().Format("2006-01-02 15:04:05"), d.Error,
+		)
+	}
+	t.write(w)
+}
+
+// followDownloads 输出下载进度，直到指定的下载（id 为空时为所有未结束的下载）结束
+func followDownloads(id string, w io.Writer) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := apiClient()
+
+	// 先订阅事件再读取当前状态，避免漏掉两者之间结束的下载
+	stream, err := c.StreamEvents(ctx, client.EventOptions{Types: []string{"download"}})
+	if err != nil {
+		return clientError(err)
+	}
+	defer stream.Close()
+
+	// 事件流会先补发历史事件，只处理比已知状态更新的事件
+	active := map[string]bool{}
+	seen := map[string]time.Time{}
+	var newest time.Time
+	var failed []string
+	if id != "" {
+		download, err := c.GetDownload(ctx, id)
+		if err != nil {
+			return clientError(err)
+		}
+		printDownloadProgress(w, *download)
+		if download.Finished() {
+			return downloadResult(*download)
+		}
+		active[id] = true
+		seen[id] = download.UpdatedAt
+	} else {
+		downloads, err := c.ListDownloads(ctx)
+		if err != nil {
+			return clientError(err)
+		}
+		for _, d := range downloads {
+			seen[d.ID] = d.UpdatedAt
+			if d.CreatedAt.After(newest) {
+				newest = d.CreatedAt
+			}
+			if !d.Finished() {
+				active[d.ID] = true
+				printDownloadProgress(w, d)
+			}
+		}
+		if len(active) == 0 {
+			fmt.Fprintln(infoOut(), "No unfinished downloads.")
+			return nil
+		}
+	}
+
+	for len(active) > 0 {
+		ev, err := stream.Next()
+		if err == io.EOF {
+			return newError(errUnreachable, "event stream closed by oneinfer service")
+		}
+		if err != nil {
+			return unreachableError(err)
+		}
+		var d client.Download
+		if err := json.Unmarshal(ev.Data, &d); err != nil || d.ID == "" {
+			continue
+		}
+		if t, ok := seen[d.ID]; ok && !d.UpdatedAt.After(t) {
+			continue
+		}
+		// 跟随所有下载时，也跟随之后排队的新任务
+		if !active[d.ID] && (id != "" || ev.Type != eventDownloadQueued || !d.CreatedAt.After(newest)) {
+			continue
+		}
+		seen[d.ID] = d.UpdatedAt
+		active[d.ID] = true
+		printDownloadProgress(w, d)
+		if d.Finished() {
+			delete(active, d.ID)
+			if err := downloadResult(d); err != nil {
+				if id != "" {
+					return err
+				}
+				failed = append(failed, d.ID)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return newError(errGeneric, "downloads %s did not complete", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// printDownloadProgress 按 --output 输出下载的一次状态：json 每行一个对象，表格模式输出一行摘要
+func printDownloadProgress(w io.Writer, d client.Download) {
+	switch outputFormat {
+	case outputJSON:
+		data, _ := json.Marshal(d)
+		fmt.Fprintf(w, "%s\n", data)
+		return
+	case outputYAML:
+		data, _ := toYAML(d)
+		fmt.Fprintf(w, "---\n%s", data)
+		return
+	}
+	line := fmt.Sprintf("%s  %s  %-11s  %s  %s", time.Now().Format("15:04:05"), d.ID, d.Status, downloadSource(d), formatBytes(d.Bytes))
+	if d.Model != "" {
+		line += "  model=" + d.Model
+	}
+	if d.Error != "" {
+		line += "  error=" + fmt.Sprintf("%q", d.Error)
+	}
+	fmt.Fprintln(w, line)
+}
+
+// downloadResult 把失败或取消的下载转换为错误
+func downloadResult(d client.Download) error {
+	switch d.Status {
+	case client.DownloadFailed:
+		return newError(errGeneric, "download %s failed: %s", d.ID, d.Error)
+	case client.DownloadCanceled:
+		return newError(errGeneric, "download %s was canceled", d.ID)
+	}
+	return nil
+}
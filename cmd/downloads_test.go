@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serve 意外终止时正在下载的任务在重启后标记为失败并删除暂存目录，不会自动续传
+func TestStartDownloadJobsFailsInterruptedJobs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(func() {
+		downloadMux.Lock()
+		downloadJobs = make(map[string]*downloadJob)
+		downloadMux.Unlock()
+	})
+	staging := makeStaging(t, "running")
+
+	jobs := []*downloadJob{
+		{downloadEvent: downloadEvent{ID: "running", Repo: "org/model"}, Status: downloadRunning},
+	}
+	data, _ := json.Marshal(jobs)
+	path, _ := downloadsPath()
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := startDownloadJobs(1); err != nil {
+		t.Fatal(err)
+	}
+	downloadMux.Lock()
+	job := downloadJobs["running"]
+	downloadMux.Unlock()
+	if job == nil || job.Status != downloadFailed || job.Error != errDownloadInterrupted || job.FinishedAt == nil {
+		t.Fatalf("job = %+v, want it failed as interrupted", job)
+	}
+	if exists(staging) {
+		t.Error("staging directory of the interrupted job was kept")
+	}
+
+	// 保存的任务列表中同样是失败状态，再次启动也不会续传
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved []*downloadJob
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || saved[0].Status != downloadFailed {
+		t.Errorf("saved jobs = %s, want the job saved as failed", data)
+	}
+}
+
+// 后台任务的导入进度写入带 job 属性的日志，而不是 serve 的终端
+func TestImportLogsToJobLogger(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	src := filepath.Join(home, "m.gguf")
+	os.WriteFile(src, []byte("GGUF job"), 0644)
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, nil)).With("job", "j1")
+	if _, err := importLocalModel("m", src, importCopy, log); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "job=j1") || !strings.Contains(out, "Importing m.gguf") {
+		t.Errorf("job log = %q, want the import progress with the job ID", out)
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// modelUsage 单个模型的磁盘占用
+type modelUsage struct {
+	Name string `json:"name"`
+	// 模型所有文件的总大小
+	Size int64 `json:"size"`
+	// 只被该模型引用的 blob 大小，删除该模型可释放的空间
+	Unique int64 `json:"unique"`
+	// 与其他模型共享的 blob 大小
+	Shared int64 `json:"shared"`
+	// 不在 blob 仓库中的文件大小（symlink/inplace 导入）
+	External int64 `json:"external"`
+}
+
+// duOutput du 命令的输出结构
+type duOutput struct {
+	Models []modelUsage `json:"models"`
+	// 所有模型大小之和，不考虑去重
+	LogicalBytes int64 `json:"logical_bytes"`
+	// blob 仓库实际占用的空间（包括未被引用的 blob）
+	BlobBytes int64 `json:"blob_bytes"`
+	// 被多个模型共享的 blob 大小
+	SharedBytes int64 `json:"shared_bytes"`
+	// 未被任何模型引用的 blob 大小，可通过 oneinfer gc 释放
+	UnreferencedBytes int64 `json:"unreferenced_bytes"`
+	// 去重节省的空间
+	SavedBytes int64 `json:"saved_bytes"`
+}
+
+var duCmd = &cobra.Command{
+	Use:   "du",
+	Short: "Show disk usage per model and for the shared blob store",
+	Args:  validArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, err := diskUsage()
+		if err != nil {
+			return err
+		}
+
+		return printResult(out, func(w io.Writer) {
+			t := &table{
+				headers:     []string{"MODEL", "SIZE", "UNIQUE", "SHARED"},
+				wideHeaders: []string{"EXTERNAL"},
+			}
+			for _, m := range out.Models {
+				t.addRow([]string{m.Name, formatBytes(m.Size), formatBytes(m.Unique), formatBytes(m.Shared)}, formatBytes(m.External))
+			}
+			t.write(w)
+			fmt.Fprintf(w, "\nBlob store: %s on disk, %s shared between models, %s saved by deduplication, %s unreferenced\n",
+				formatBytes(out.BlobBytes), formatBytes(out.SharedBytes), formatBytes(out.SavedBytes), formatBytes(out.UnreferencedBytes))
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(duCmd)
+}
+
+// diskUsage 统计每个模型以及 blob 仓库的磁盘占用
+func diskUsage() (duOutput, error) {
+	out := duOutput{Models: []modelUsage{}}
+
+	models, err := loadRegistry()
+	if err != nil {
+		return out, err
+	}
+
+	// 统计每个 blob 被多少个模型引用
+	refs := map[string]int{}
+	sizes := map[string]int64{}
+	for _, m := range models {
+		seen := map[string]bool{}
+		for _, f := range m.Files {
+			if f.External != "" || seen[f.Digest] {
+				continue
+			}
+			seen[f.Digest] = true
+			refs[f.Digest]++
+			sizes[f.Digest] = f.Size
+		}
+	}
+
+	for _, m := range models {
+		usage := modelUsage{Name: m.Name, Size: m.Size}
+		seen := map[string]bool{}
+		for _, f := range m.Files {
+			switch {
+			case f.External != "":
+				usage.External += f.Size
+			case seen[f.Digest]:
+			case refs[f.Digest] > 1:
+				usage.Shared += f.Size
+			default:
+				usage.Unique += f.Size
+			}
+			seen[f.Digest] = true
+		}
+		// 旧版本注册的模型没有清单，直接统计文件大小
+		if m.Files == nil {
+			if info, err := os.Stat(m.Path); err == nil && info.Mode().IsRegular() {
+				usage.Size = info.Size()
+				usage.External = info.Size()
+			}
+		}
+		out.Models = append(out.Models, usage)
+		out.LogicalBytes += usage.Size
+	}
+
+	var referencedBytes int64
+	for digest, n := range refs {
+		referencedBytes += sizes[digest]
+		if n > 1 {
+			out.SharedBytes += sizes[digest]
+			out.SavedBytes += sizes[digest] * int64(n-1)
+		}
+	}
+
+	// blob 仓库实际占用
+	dir, err := blobsDir()
+	if err != nil {
+		return out, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return out, err
+	}
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil {
+			out.BlobBytes += info.Size()
+		}
+	}
+	if out.BlobBytes > referencedBytes {
+		out.UnreferencedBytes = out.BlobBytes - referencedBytes
+	}
+
+	return out, nil
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"oneinfer/client"
+
+	"github.com/spf13/cobra"
+)
+
+// errorKind CLI 错误类型，每种类型对应一个固定的退出码
+type errorKind string
+
+const (
+	errGeneric     errorKind = "error"
+	errValidation  errorKind = "validation"
+	errNotFound    errorKind = "not_found"
+	errConflict    errorKind = "conflict"
+	errUnreachable errorKind = "server_unreachable"
+	errAuth        errorKind = "auth"
+)
+
+// exitCodes 错误类型到退出码的映射，修改时需要同步 readme 中的说明
+var exitCodes = map[errorKind]int{
+	errGeneric:     1,
+	errValidation:  2,
+	errNotFound:    3,
+	errConflict:    4,
+	errUnreachable: 5,
+	errAuth:        6,
+}
+
+// exitCodeHelp 退出码说明，显示在根命令的帮助信息中
+const exitCodeHelp = `Exit codes:
+  0  success
+  1  unexpected error
+  2  invalid arguments or input (validation)
+  3  model or instance not found
+  4  conflict (already exists, port in use)
+  5  oneinfer serve is unreachable
+  6  authentication or authorization failure`
+
+// cliError 带类型的 CLI 错误
+type cliError struct {
+	kind errorKind
+	msg  string
+	err  error
+}
+
+func (e *cliError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("%s: %v", e.msg, e.err)
+	}
+	return e.msg
+}
+
+func (e *cliError) Unwrap() error {
+	return e.err
+}
+
+// newError 创建指定类型的错误
+func newError(kind errorKind, format string, args ...interface{}) error {
+	return &cliError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// wrapError 用指定类型包装已有错误
+func wrapError(kind errorKind, err error, format string, args ...interface{}) error {
+	return &cliError{kind: kind, msg: fmt.Sprintf(format, args...), err: err}
+}
+
+// kindOf 返回错误类型，未标注类型的错误视为 errGeneric
+func kindOf(err error) errorKind {
+	var ce *cliError
+	if errors.As(err, &ce) {
+		return ce.kind
+	}
+	return errGeneric
+}
+
+// ExitCode 返回错误对应的进程退出码
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return exitCodes[kindOf(err)]
+}
+
+// validArgs 把 cobra 的参数校验错误标记为 validation 错误
+func validArgs(args cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, a []string) error {
+		if err := args(cmd, a); err != nil {
+			return &cliError{kind: errValidation, msg: err.Error()}
+		}
+		return nil
+	}
+}
+
+// unreachableError 连接 serve 失败时的错误
+func unreachableError(err error) error {
+	return wrapError(errUnreachable, err, "unable to connect to oneinfer service (is 'oneinfer serve' running?)")
+}
+
+// clientError 把客户端返回的错误转换为对应类型的 CLI 错误：服务端错误按状态码分类，其他错误视为无法连接
+func clientError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		return unreachableError(err)
+	}
+
+	kind := errGeneric
+	switch apiErr.StatusCode {
+	case http.StatusBadRequest:
+		kind = errValidation
+	case http.StatusNotFound:
+		kind = errNotFound
+	case http.StatusConflict:
+		kind = errConflict
+	case http.StatusUnauthorized, http.StatusForbidden:
+		kind = errAuth
+	}
+	return &cliError{kind: kind, msg: apiErr.Error()}
+}
+
+// httpStatus 返回错误类型对应的 HTTP 状态码，与 clientError 相反
+func httpStatus(err error) int {
+	switch kindOf(err) {
+	case errValidation:
+		return http.StatusBadRequest
+	case errNotFound:
+		return http.StatusNotFound
+	case errConflict:
+		return http.StatusConflict
+	case errAuth:
+		return http.StatusForbidden
+	case errUnreachable:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,489 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"oneinfer/client"
+
+	"github.com/spf13/cobra"
+)
+
+// 事件类型，按前缀分组：model.*、download.*、registry.*、library.*
+const (
+	eventModelStarted      = "model.started"
+	eventModelReady        = "model.ready"
+	eventModelCrashed      = "model.crashed"
+	eventModelRestarted    = "model.restarted"
+	eventModelFailed       = "model.failed"
+	eventModelStopped      = "model.stopped"
+	eventModelOOMKilled    = "model.oom_killed"
+	eventModelRefused      = "model.refused"
+	eventDownloadQueued    = "download.queued"
+	eventDownloadStarted   = "download.started"
+	eventDownloadProgress  = "download.progress"
+	eventDownloadCompleted = "download.completed"
+	eventDownloadFailed    = "download.failed"
+	eventDownloadCanceled  = "download.canceled"
+	eventRegistryChanged   = "registry.changed"
+	eventLibraryAdded      = "library.added"
+	eventLibraryAddFailed  = "library.add_failed"
+)
+
+const (
+	// eventHistory 保留的最近事件数，客户端重连时按 Last-Event-ID 补发
+	eventHistory = 256
+	// eventBuffer 每个订阅者的缓冲区大小，写满说明客户端太慢，断开后由其重连补发
+	eventBuffer = 64
+	// eventHeartbeat SSE 心跳间隔，防止代理因空闲断开连接
+	eventHeartbeat = 15 * time.Second
+	// registryPollInterval 检查注册表变化的间隔，注册表由 CLI 进程直接修改
+	registryPollInterval = 2 * time.Second
+	// downloadProgressInterval CLI 上报下载进度的间隔
+	downloadProgressInterval = 2 * time.Second
+)
+
+// serverEvent serve 推送的一个事件
+type serverEvent struct {
+	ID   uint64          `json:"id"`
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// modelEvent model.* 事件的数据
+type modelEvent struct {
+	ModelProcessStatus
+	Error          string  `json:"error,omitempty"`
+	StartupSeconds float64 `json:"startup_seconds,omitempty"`
+}
+
+// downloadEvent download.* 事件的数据，由执行下载的 CLI 进程上报或由 serve 的下载任务产生
+type downloadEvent struct {
+	ID       string `json:"id,omitempty"` // serve 分配的下载 ID
+	Platform string `json:"platform"`
+	Repo     string `json:"repo"`
+	Revision string `json:"revision,omitempty"`
+	Files    string `json:"files,omitempty"`
+	Bytes    int64  `json:"bytes"`
+	Error    string `json:"error,omitempty"`
+}
+
+// registryEvent registry.changed 事件的数据
+type registryEvent struct {
+	Added   []modelInfo `json:"added"`
+	Updated []modelInfo `json:"updated"`
+	Removed []string    `json:"removed"`
+}
+
+// eventBroker 把事件分发给所有订阅者，并保留最近的事件供重连补发
+type eventBroker struct {
+	mu          sync.Mutex
+	nextID      uint64
+	recent      []serverEvent
+	subscribers map[chan serverEvent]bool
+}
+
+var eventHub = &eventBroker{subscribers: map[chan serverEvent]bool{}}
+
+// publishEvent 发布一个事件，data 序列化为事件数据
+func publishEvent(eventType string, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	eventHub.publish(eventType, raw)
+}
+
+func (b *eventBroker) publish(eventType string, data json.RawMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	ev := serverEvent{ID: b.nextID, Type: eventType, Time: time.Now().UTC(), Data: data}
+	b.recent = append(b.recent, ev)
+	if len(b.recent) > eventHistory {
+		b.recent = b.recent[len(b.recent)-eventHistory:]
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// 订阅者跟不上，关闭后客户端会带着 Last-Event-ID 重连
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe 注册订阅者，返回 lastID 之后仍保留的事件
+func (b *eventBroker) subscribe(lastID uint64) (chan serverEvent, []serverEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := make(chan serverEvent, eventBuffer)
+	b.subscribers[ch] = true
+	var replay []serverEvent
+	for _, ev := range b.recent {
+		if ev.ID > lastID {
+			replay = append(replay, ev)
+		}
+	}
+	return ch, replay
+}
+
+func (b *eventBroker) unsubscribe(ch chan serverEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers[ch] {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publishModelEvent 发布 model.* 事件。调用方需持有 modelMux。
+func publishModelEvent(eventType string, mp *ModelProcess, errMsg string, startup time.Duration) {
+	publishEvent(eventType, modelEvent{
+		ModelProcessStatus: mp.status(),
+		Error:              errMsg,
+		StartupSeconds:     startup.Seconds(),
+	})
+}
+
+// matchEventTypes 判断事件类型是否匹配过滤条件，条件为完整类型或前缀（如 model）
+func matchEventTypes(eventType string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, f := range filters {
+		if eventType == f || strings.HasPrefix(eventType, strings.TrimSuffix(f, ".")+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// eventsHandler 以 SSE 推送事件，支持 ?type= 过滤和 Last-Event-ID 补发
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	var filters []string
+	for _, v := range r.URL.Query()["type"] {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				filters = append(filters, f)
+			}
+		}
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	since, _ := strconv.ParseUint(lastID, 10, 64)
+
+	ch, replay := eventHub.subscribe(since)
+	defer eventHub.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, "retry: 3000\n\n")
+	for _, ev := range replay {
+		if matchEventTypes(ev.Type, filters) {
+			writeSSE(w, ev)
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			io.WriteString(w, ": ping\n\n")
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !matchEventTypes(ev.Type, filters) {
+				continue
+			}
+			writeSSE(w, ev)
+		}
+		flusher.Flush()
+	}
+}
+
+// writeSSE 按 SSE 格式写出一个事件
+func writeSSE(w io.Writer, ev serverEvent) {
+	data, _ := json.Marshal(ev)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+}
+
+// postEventHandler 接收 CLI 进程上报的下载事件，其他事件只能由 serve 自己产生
+func postEventHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	switch req.Type {
+	case eventDownloadStarted, eventDownloadProgress, eventDownloadCompleted, eventDownloadFailed:
+	default:
+		http.Error(w, fmt.Sprintf("Event type %q cannot be published by clients", req.Type), http.StatusBadRequest)
+		return
+	}
+	var data downloadEvent
+	if err := json.Unmarshal(req.Data, &data); err != nil {
+		http.Error(w, "Invalid event data", http.StatusBadRequest)
+		return
+	}
+	publishEvent(req.Type, recordDownload(req.Type, data))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// notifyDownload 把下载事件上报给 serve，serve 未运行时忽略
+func notifyDownload(eventType string, data downloadEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	apiClient().PublishEvent(ctx, eventType, data)
+}
+
+// watchRegistry 定期检查注册表，发生变化时发布 registry.changed 事件
+func watchRegistry() {
+	path, err := registryPath()
+	if err != nil {
+		return
+	}
+	snapshot := func() map[string]string {
+		models, err := listModels()
+		if err != nil {
+			return nil
+		}
+		entries := make(map[string]string, len(models))
+		for _, m := range models {
+			data, _ := json.Marshal(m)
+			entries[m.Name] = string(data)
+		}
+		return entries
+	}
+
+	var lastMod time.Time
+	var lastSize int64
+	if info, err := os.Stat(path); err == nil {
+		lastMod, lastSize = info.ModTime(), info.Size()
+	}
+	known := snapshot()
+	for range time.Tick(registryPollInterval) {
+		info, err := os.Stat(path)
+		if err != nil || (info.ModTime().Equal(lastMod) && info.Size() == lastSize) {
+			continue
+		}
+		lastMod, lastSize = info.ModTime(), info.Size()
+		current := snapshot()
+		if current == nil {
+			continue
+		}
+
+		change := registryEvent{Added: []modelInfo{}, Updated: []modelInfo{}, Removed: []string{}}
+		for _, name := range sortedNames(current) {
+			old, ok := known[name]
+			if ok && old == current[name] {
+				continue
+			}
+			var m modelInfo
+			json.Unmarshal([]byte(current[name]), &m)
+			if ok {
+				change.Updated = append(change.Updated, m)
+			} else {
+				change.Added = append(change.Added, m)
+			}
+		}
+		for _, name := range sortedNames(known) {
+			if _, ok := current[name]; !ok {
+				change.Removed = append(change.Removed, name)
+			}
+		}
+		known = current
+		if len(change.Added)+len(change.Updated)+len(change.Removed) > 0 {
+			publishEvent(eventRegistryChanged, change)
+		}
+	}
+}
+
+// sortedNames 返回排序后的 map 键
+func sortedNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// events 命令
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Stream lifecycle events from the oneinfer service",
+	Long: `Print model lifecycle events (started, ready, crashed, oom_killed, restarted, failed, stopped, refused),
+download progress, registry changes and models added from the web UI as they happen, until interrupted.
+
+Filter with --type, given a full type such as model.ready or a group such as model.
+With --output json each event is printed as one JSON object per line.`,
+	Args: validArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		types, _ := cmd.Flags().GetStringSlice("type")
+
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		defer signal.Stop(interrupted)
+
+		errc := make(chan error, 1)
+		go func() { errc <- streamEvents(types, os.Stdout) }()
+		select {
+		case <-interrupted:
+			return nil
+		case err := <-errc:
+			return err
+		}
+	},
+}
+
+func init() {
+	eventsCmd.Flags().StringSlice("type", nil, "Only show events of these types or groups (model, download, registry, library)")
+	rootCmd.AddCommand(eventsCmd)
+}
+
+// streamEvents 连接 /api/v1/events 并逐条输出事件
+func streamEvents(types []string, w io.Writer) error {
+	stream, err := apiClient().StreamEvents(context.Background(), client.EventOptions{Types: types})
+	if err != nil {
+		return clientError(err)
+	}
+	defer stream.Close()
+
+	for {
+		ev, err := stream.Next()
+		if err == io.EOF {
+			return newError(errUnreachable, "event stream closed by oneinfer service")
+		}
+		if err != nil {
+			return unreachableError(err)
+		}
+		if err := printEvent(w, serverEvent(*ev)); err != nil {
+			return err
+		}
+	}
+}
+
+// printEvent 按 --output 输出一个事件：json 每行一个对象，yaml 以 --- 分隔，表格模式输出一行摘要
+func printEvent(w io.Writer, ev serverEvent) error {
+	switch outputFormat {
+	case outputJSON:
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n", data)
+		return nil
+	case outputYAML:
+		data, err := toYAML(ev)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "---\n%s", data)
+		return nil
+	}
+	fmt.Fprintf(w, "%s  %-18s  %s\n", ev.Time.Local().Format("15:04:05"), ev.Type, eventSummary(ev))
+	return nil
+}
+
+// eventSummary 返回事件的一行摘要
+func eventSummary(ev serverEvent) string {
+	switch {
+	case strings.HasPrefix(ev.Type, "model."):
+		var data modelEvent
+		json.Unmarshal(ev.Data, &data)
+		name := data.Name
+		if name == "" {
+			name = data.Model
+		}
+		s := fmt.Sprintf("%d %s http://%s:%d", data.ID, name, data.Host, data.Port)
+		if data.StartupSeconds > 0 {
+			s += fmt.Sprintf(" startup=%.1fs", data.StartupSeconds)
+		}
+		if ev.Type == eventModelRestarted || ev.Type == eventModelCrashed || ev.Type == eventModelFailed {
+			s += fmt.Sprintf(" restarts=%d", data.Restarts)
+		}
+		if data.Error != "" {
+			s += " error=" + strconv.Quote(data.Error)
+		}
+		return s
+	case strings.HasPrefix(ev.Type, "download."):
+		var data downloadEvent
+		json.Unmarshal(ev.Data, &data)
+		s := data.Platform + ":" + data.Repo
+		if data.ID != "" {
+			s = data.ID + " " + s
+		}
+		if data.Revision != "" {
+			s += "@" + data.Revision
+		}
+		if data.Files != "" {
+			s += " " + data.Files
+		}
+		if data.Bytes > 0 {
+			s += " " + formatBytes(data.Bytes)
+		}
+		if data.Error != "" {
+			s += " error=" + strconv.Quote(data.Error)
+		}
+		return s
+	case ev.Type == eventRegistryChanged:
+		var data registryEvent
+		json.Unmarshal(ev.Data, &data)
+		var parts []string
+		for _, m := range data.Added {
+			parts = append(parts, "+"+m.Name)
+		}
+		for _, m := range data.Updated {
+			parts = append(parts, "~"+m.Name)
+		}
+		for _, name := range data.Removed {
+			parts = append(parts, "-"+name)
+		}
+		return strings.Join(parts, " ")
+	case strings.HasPrefix(ev.Type, "library."):
+		var data libraryAddEvent
+		json.Unmarshal(ev.Data, &data)
+		s := data.Request.Platform + ":" + data.Request.Repo
+		if data.Request.Platform == "local" {
+			s = data.Request.Path
+		}
+		if data.Model != nil {
+			s += " -> " + data.Model.Name
+		}
+		if data.Error != "" {
+			s += " error=" + strconv.Quote(data.Error)
+		}
+		return s
+	}
+	return string(ev.Data)
+}
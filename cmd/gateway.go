@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// 通过 serve 转发到模型 OpenAI 兼容接口的请求，按注册表名称统计
+var (
+	ttftBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60}
+
+	gatewayRequests = newCounterVec("oneinfer_gateway_requests_total",
+		"Requests proxied to model OpenAI-compatible endpoints.", "model", "code")
+	gatewayRequestDuration = newHistogramVec("oneinfer_gateway_request_duration_seconds",
+		"Total time of proxied requests, including streaming the whole response.", startBuckets, "model")
+	gatewayTimeToFirstToken = newHistogramVec("oneinfer_gateway_time_to_first_token_seconds",
+		"Time from receiving a streaming request until the first chunk with generated content arrives from the model. Non-streaming requests are not counted.", ttftBuckets, "model")
+	gatewayTokens = newCounterVec("oneinfer_gateway_tokens_total",
+		"Tokens reported by the model for proxied requests.", "model", "kind")
+)
+
+// gatewayMaxBuffer 非流式响应中查找 usage 时最多缓存的字节数
+const gatewayMaxBuffer = 4 << 20
+
+// modelProxyHandler 把 /models/{id}/v1/... 转发到模型的 OpenAI 兼容接口，保留流式响应
+func modelProxyHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid process ID", http.StatusBadRequest)
+		return
+	}
+
+	modelMux.Lock()
+	mp, ok := models[id]
+	var addr, name, status string
+	if ok {
+		addr, name, status = mp.dialAddr(), mp.displayName(), mp.Status
+	}
+	modelMux.Unlock()
+	if !ok {
+		http.Error(w, "Process not found", http.StatusNotFound)
+		return
+	}
+	if status != statusRunning {
+		http.Error(w, fmt.Sprintf("Model %d is %s", id, status), http.StatusServiceUnavailable)
+		return
+	}
+
+	start := time.Now()
+	code := http.StatusBadGateway
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+			req.URL.Host = addr
+			req.URL.Path = "/v1/" + vars["path"]
+			req.URL.RawPath = ""
+			req.Host = addr
+			// 浏览器的 Cookie 和来源信息不需要转给模型
+			req.Header.Del("Cookie")
+			req.Header.Del("Origin")
+		},
+		// 流式响应逐块转发
+		FlushInterval: -1,
+		ModifyResponse: func(resp *http.Response) error {
+			code = resp.StatusCode
+			stream := strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+			resp.Body = &usageReader{body: resp.Body, model: name, start: start, stream: stream}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			requestLogger(r).Warn("proxy to model failed", "id", id, "model", name, "error", err)
+			http.Error(w, "Model is unreachable: "+err.Error(), http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+
+	gatewayRequests.inc(name, strconv.Itoa(code))
+	gatewayRequestDuration.observe(time.Since(start).Seconds(), name)
+}
+
+// usageReader 包装模型的响应体，从响应中读取 token 用量。
+// 流式响应逐行处理 data 行，在第一个带生成内容的块到达时记录首 token 延迟；
+// 普通响应在读完后整体解析，其首字节时间就是总耗时，不计入首 token 延迟。
+type usageReader struct {
+	body   io.ReadCloser
+	model  string
+	start  time.Time
+	stream bool
+
+	first    bool
+	buf      bytes.Buffer
+	prompt   int
+	complete int
+	done     bool
+}
+
+// tokenUsage OpenAI 的 usage 字段和 llama-server 的 timings 字段
+type tokenUsage struct {
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Timings *struct {
+		PromptN    int `json:"prompt_n"`
+		PredictedN int `json:"predicted_n"`
+	} `json:"timings"`
+}
+
+func (u *usageReader) Read(p []byte) (int, error) {
+	n, err := u.body.Read(p)
+	if n > 0 {
+		if u.buf.Len() < gatewayMaxBuffer {
+			u.buf.Write(p[:n])
+		}
+		if u.stream {
+			u.scanLines()
+		}
+	}
+	if err == io.EOF {
+		u.finish()
+	}
+	return n, err
+}
+
+func (u *usageReader) Close() error {
+	u.finish()
+	return u.body.Close()
+}
+
+// scanLines 处理缓冲区中完整的 SSE 行
+func (u *usageReader) scanLines() {
+	for {
+		line, err := u.buf.ReadBytes('\n')
+		if err != nil {
+			// 不完整的行放回缓冲区
+			rest := append([]byte{}, line...)
+			u.buf.Reset()
+			u.buf.Write(rest)
+			return
+		}
+		if data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data: ")); ok {
+			if !u.first && hasGeneratedContent(data) {
+				u.first = true
+				gatewayTimeToFirstToken.observe(time.Since(u.start).Seconds(), u.model)
+			}
+			u.parseUsage(data)
+		}
+	}
+}
+
+// streamChunk 流式响应中一个块的 choices，兼容 chat 和 completions 接口
+type streamChunk struct {
+	Choices []struct {
+		Text  string `json:"text"`
+		Delta struct {
+			Content          string          `json:"content"`
+			ReasoningContent string          `json:"reasoning_content"`
+			ToolCalls        json.RawMessage `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// hasGeneratedContent 判断流式块是否带有模型生成的内容。
+// 只有 role 的第一个块、[DONE] 和只有 usage 的块都不算。
+func hasGeneratedContent(data []byte) bool {
+	var chunk streamChunk
+	if json.Unmarshal(data, &chunk) != nil {
+		return false
+	}
+	for _, c := range chunk.Choices {
+		if c.Text != "" || c.Delta.Content != "" || c.Delta.ReasoningContent != "" || len(c.Delta.ToolCalls) > 0 && string(c.Delta.ToolCalls) != "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseUsage 从一个 JSON 对象中读取 token 用量，有 usage 时优先使用
+func (u *usageReader) parseUsage(data []byte) {
+	if !bytes.Contains(data, []byte(`"usage"`)) && !bytes.Contains(data, []byte(`"timings"`)) {
+		return
+	}
+	var usage tokenUsage
+	if json.Unmarshal(data, &usage) != nil {
+		return
+	}
+	switch {
+	case usage.Usage != nil:
+		u.prompt, u.complete = usage.Usage.PromptTokens, usage.Usage.CompletionTokens
+	case usage.Timings != nil:
+		u.prompt, u.complete = usage.Timings.PromptN, usage.Timings.PredictedN
+	}
+}
+
+// finish 记录 token 用量，只执行一次
+func (u *usageReader) finish() {
+	if u.done {
+		return
+	}
+	u.done = true
+	if !u.stream {
+		u.parseUsage(u.buf.Bytes())
+	}
+	u.buf.Reset()
+	if u.prompt > 0 {
+		gatewayTokens.add(float64(u.prompt), u.model, "prompt")
+	}
+	if u.complete > 0 {
+		gatewayTokens.add(float64(u.complete), u.model, "completion")
+	}
+}
+
+// serveChatPage 返回聊天页面
+func serveChatPage(w http.ResponseWriter, r *http.Request) {
+	data, err := staticFiles.ReadFile("static/chat.html")
+	if err != nil {
+		http.Error(w, "Could not load chat.html", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(data)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// histogramCount 返回标签值对应的观测次数
+func histogramCount(h *histogramVec, values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if data, ok := h.values[strings.Join(values, "\xff")]; ok {
+		return data.count
+	}
+	return 0
+}
+
+// readUsage 逐字节读完响应体，模拟分块到达的流式响应
+func readUsage(t *testing.T, model, body string, stream bool) *usageReader {
+	t.Helper()
+	u := &usageReader{body: io.NopCloser(iotest.OneByteReader(strings.NewReader(body))), model: model, start: time.Now(), stream: stream}
+	if _, err := io.Copy(io.Discard, u); err != nil {
+		t.Fatal(err)
+	}
+	u.Close()
+	return u
+}
+
+func TestUsageReaderStreaming(t *testing.T) {
+	body := `data: {"choices":[{"delta":{"role":"assistant"}}]}
+
+data: {"choices":[{"delta":{"content":"Hel"}}]}
+
+data: {"choices":[{"delta":{"content":"lo"}}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":2}}
+
+data: [DONE]
+
+`
+	u := readUsage(t, "ttft-stream", body, true)
+	if u.prompt != 12 || u.complete != 2 {
+		t.Errorf("usage = %d/%d, want 12/2", u.prompt, u.complete)
+	}
+	if n := histogramCount(gatewayTimeToFirstToken, "ttft-stream"); n != 1 {
+		t.Errorf("time to first token observed %d times, want 1", n)
+	}
+}
+
+// 只有 role 或 usage 的流没有生成内容，不记录首 token 延迟
+func TestUsageReaderStreamingWithoutContent(t *testing.T) {
+	body := "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"\"},\"finish_reason\":\"length\"}],\"timings\":{\"prompt_n\":5,\"predicted_n\":0}}\n\n" +
+		"data: [DONE]\n\n"
+	u := readUsage(t, "ttft-empty", body, true)
+	if u.prompt != 5 {
+		t.Errorf("prompt tokens = %d, want 5", u.prompt)
+	}
+	if n := histogramCount(gatewayTimeToFirstToken, "ttft-empty"); n != 0 {
+		t.Errorf("time to first token observed %d times, want 0", n)
+	}
+}
+
+func TestUsageReaderNonStreaming(t *testing.T) {
+	body := `{"choices":[{"message":{"content":"Hello"}}],"usage":{"prompt_tokens":3,"completion_tokens":1}}`
+	u := readUsage(t, "ttft-plain", body, false)
+	if u.prompt != 3 || u.complete != 1 {
+		t.Errorf("usage = %d/%d, want 3/1", u.prompt, u.complete)
+	}
+	if n := histogramCount(gatewayTimeToFirstToken, "ttft-plain"); n != 0 {
+		t.Errorf("time to first token observed %d times for a non-streaming response", n)
+	}
+}
+
+func TestHasGeneratedContent(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{`{"choices":[{"delta":{"content":"x"}}]}`, true},
+		{`{"choices":[{"delta":{"reasoning_content":"thinking"}}]}`, true},
+		{`{"choices":[{"delta":{"tool_calls":[{"index":0}]}}]}`, true},
+		{`{"choices":[{"text":"x"}]}`, true},
+		{`{"choices":[{"delta":{"role":"assistant","content":""}}]}`, false},
+		{`{"choices":[{"delta":{"tool_calls":null}}]}`, false},
+		{`{"choices":[],"usage":{"prompt_tokens":1}}`, false},
+		{`[DONE]`, false},
+	}
+	for _, tt := range tests {
+		if got := hasGeneratedContent([]byte(tt.data)); got != tt.want {
+			t.Errorf("hasGeneratedContent(%s) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// gcItem gc 删除（或 dry-run 时将要删除）的一项
+type gcItem struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"` // blob、partial 或 staging
+	Size   int64  `json:"size"`
+	Reason string `json:"reason"`
+}
+
+// gcOutput gc 命令的输出结构
+type gcOutput struct {
+	DryRun     bool     `json:"dry_run"`
+	Removed    []gcItem `json:"removed"`
+	FreedBytes int64    `json:"freed_bytes"`
+}
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove unreferenced blobs and leftover partial downloads",
+	Args:  validArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		grace, _ := cmd.Flags().GetDuration("grace")
+
+		out, err := collectGarbage(dryRun, grace)
+		if err != nil {
+			return err
+		}
+
+		return printResult(out, func(w io.Writer) {
+			if len(out.Removed) == 0 {
+				fmt.Fprintln(w, "Nothing to clean up.")
+				return
+			}
+			t := &table{headers: []string{"KIND", "SIZE", "PATH"}, wideHeaders: []string{"REASON"}}
+			for _, item := range out.Removed {
+				t.addRow([]string{item.Kind, formatBytes(item.Size), item.Path}, item.Reason)
+			}
+			t.write(w)
+			verb := "Freed"
+			if out.DryRun {
+				verb = "Would free"
+			}
+			fmt.Fprintf(w, "%s %s.\n", verb, formatBytes(out.FreedBytes))
+		})
+	},
+}
+
+func init() {
+	gcCmd.Flags().Bool("dry-run", false, "Only show what would be removed")
+	gcCmd.Flags().Duration("grace", time.Hour, "Keep partial downloads and blobs modified more recently than this, as they may belong to an add in progress")
+	rootCmd.AddCommand(gcCmd)
+}
+
+// collectGarbage 删除未被任何模型引用的 blob、残留的 .partial 文件和下载暂存目录。
+// 整个过程持有注册表锁，进行中的导入租约里的 blob 不会被删除：
+// 硬链接导入的 blob 保留源文件的修改时间，不能只靠宽限期判断。
+func collectGarbage(dryRun bool, grace time.Duration) (gcOutput, error) {
+	out := gcOutput{DryRun: dryRun, Removed: []gcItem{}}
+	cutoff := time.Now().Add(-grace)
+
+	unlock, err := lockRegistry()
+	if err != nil {
+		return out, err
+	}
+	defer unlock()
+	models, err := loadRegistry()
+	if err != nil {
+		return out, err
+	}
+	leased, err := leasedBlobs()
+	if err != nil {
+		return out, err
+	}
+	referenced := map[string]bool{}
+	for _, m := range models {
+		for _, f := range m.Files {
+			if f.External == "" {
+				referenced["sha256-"+strings.TrimPrefix(f.Digest, "sha256:")] = true
+			}
+		}
+	}
+	for digest := range leased {
+		referenced["sha256-"+strings.TrimPrefix(digest, "sha256:")] = true
+	}
+
+	remove := func(item gcItem) error {
+		if !dryRun {
+			if err := os.RemoveAll(item.Path); err != nil {
+				return fmt.Errorf("failed to remove %s: %v", item.Path, err)
+			}
+		}
+		out.Removed = append(out.Removed, item)
+		out.FreedBytes += item.Size
+		return nil
+	}
+
+	// blob 仓库
+	dir, err := blobsDir()
+	if err != nil {
+		return out, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return out, err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		item := gcItem{Path: filepath.Join(dir, entry.Name()), Size: info.Size()}
+		switch {
+		case strings.HasSuffix(entry.Name(), ".partial"):
+			item.Kind, item.Reason = "partial", "interrupted copy or download"
+		case strings.HasPrefix(entry.Name(), "sha256-") && !referenced[entry.Name()]:
+			item.Kind, item.Reason = "blob", "not referenced by any model"
+		default:
+			continue
+		}
+		if err := remove(item); err != nil {
+			return out, err
+		}
+	}
+
+	// 下载暂存目录
+	root, err := oneinferDir()
+	if err != nil {
+		return out, err
+	}
+	tmpDir := filepath.Join(root, "tmp")
+	entries, err = os.ReadDir(tmpDir)
+	if err != nil && !os.IsNotExist(err) {
+		return out, err
+	}
+	pending, err := pendingStagingDirs()
+	if err != nil {
+		return out, err
+	}
+	for _, entry := range entries {
+		if pending[entry.Name()] {
+			continue
+		}
+		path := filepath.Join(tmpDir, entry.Name())
+		size, modTime := treeUsage(path)
+		if modTime.After(cutoff) {
+			continue
+		}
+		if err := remove(gcItem{Path: path, Kind: "staging", Size: size, Reason: "leftover download staging directory"}); err != nil {
+			return out, err
+		}
+	}
+
+	return out, nil
+}
+
+// pendingStagingDirs 返回 downloads.json 中未结束任务的暂存目录名，serve 重启后要在其中续传
+func pendingStagingDirs() (map[string]bool, error) {
+	path, err := downloadsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var jobs []*downloadJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	pending := map[string]bool{}
+	for _, job := range jobs {
+		if job.finished() {
+			continue
+		}
+		dir, err := downloadStagingDir(job.ID)
+		if err != nil {
+			return nil, err
+		}
+		pending[filepath.Base(dir)] = true
+	}
+	return pending, nil
+}
+
+// treeUsage 返回目录树的总大小以及其中最新的修改时间
+func treeUsage(root string) (int64, time.Time) {
+	var size int64
+	var newest time.Time
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	return size, newest
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// makeStaging 创建一个早于宽限期的暂存目录
+func makeStaging(t *testing.T, id string) string {
+	t.Helper()
+	dir, err := downloadStagingDir(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "model.gguf.partial")
+	os.WriteFile(file, []byte("partial"), 0644)
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(file, old, old)
+	os.Chtimes(dir, old, old)
+	return dir
+}
+
+// exists 判断路径是否存在
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// 未结束任务的暂存目录在 serve 重启后还要续传，gc 不能删除
+func TestCollectGarbageKeepsPendingStaging(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	queued := makeStaging(t, "queued")
+	running := makeStaging(t, "running")
+	completed := makeStaging(t, "completed")
+	orphan := makeStaging(t, "orphan")
+
+	jobs := []*downloadJob{
+		{downloadEvent: downloadEvent{ID: "queued"}, Status: downloadQueued},
+		{downloadEvent: downloadEvent{ID: "running"}, Status: downloadRunning},
+		{downloadEvent: downloadEvent{ID: "completed"}, Status: downloadCompleted},
+	}
+	data, _ := json.Marshal(jobs)
+	path, _ := downloadsPath()
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := collectGarbage(false, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := map[string]bool{}
+	for _, item := range out.Removed {
+		removed[item.Path] = true
+	}
+	for _, dir := range []string{queued, running} {
+		if removed[dir] {
+			t.Errorf("staging directory of an unfinished job was removed: %s", dir)
+		}
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s: %v", dir, err)
+		}
+	}
+	for _, dir := range []string{completed, orphan} {
+		if !removed[dir] {
+			t.Errorf("leftover staging directory was kept: %s", dir)
+		}
+	}
+}
+
+// downloads.json 无法解析时不清理暂存目录
+func TestCollectGarbageCorruptDownloads(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := makeStaging(t, "queued")
+	path, _ := downloadsPath()
+	os.WriteFile(path, []byte("{"), 0644)
+
+	if _, err := collectGarbage(false, time.Hour); err == nil {
+		t.Error("expected an error for a corrupt downloads.json")
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("staging directory was removed: %v", err)
+	}
+}
+
+// 硬链接导入的 blob 保留源文件的旧修改时间，注册之前只能靠租约保护
+func TestCollectGarbageKeepsLeasedBlobs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	old := time.Now().Add(-2 * time.Hour)
+	source := func(name, content string) string {
+		path := filepath.Join(home, name)
+		os.WriteFile(path, []byte(content), 0644)
+		os.Chtimes(path, old, old)
+		return path
+	}
+
+	lease, err := newBlobLease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.release()
+	importing, err := storeBlob(source("importing.gguf", "GGUF importing"), importHardlink, lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphan, err := storeBlob(source("orphan.gguf", "GGUF orphan"), importHardlink, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := collectGarbage(false, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if path, _ := importing.filePath(); !exists(path) {
+		t.Error("blob of an import in progress was removed")
+	}
+	if path, _ := orphan.filePath(); exists(path) {
+		t.Error("unreferenced blob was kept")
+	}
+}
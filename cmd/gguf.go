@@ -0,0 +1,402 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+)
+
+// GGUF 元数据值类型
+const (
+	ggufTypeUint8 = iota
+	ggufTypeInt8
+	ggufTypeUint16
+	ggufTypeInt16
+	ggufTypeUint32
+	ggufTypeInt32
+	ggufTypeFloat32
+	ggufTypeBool
+	ggufTypeString
+	ggufTypeArray
+	ggufTypeUint64
+	ggufTypeInt64
+	ggufTypeFloat64
+)
+
+// ggufMagic GGUF 文件头
+const ggufMagic = "GGUF"
+
+// ggufDefaultAlignment 未指定 general.alignment 时的默认对齐
+const ggufDefaultAlignment = 32
+
+// ggufMaxArrayDepth 数组嵌套的最大层数，防止损坏的文件导致过深的递归
+const ggufMaxArrayDepth = 4
+
+// ggmlTypeSizes ggml 张量类型的块大小（元素数）和每块字节数
+var ggmlTypeSizes = map[uint32][2]uint64{
+	0:  {1, 4},     // F32
+	1:  {1, 2},     // F16
+	2:  {32, 18},   // Q4_0
+	3:  {32, 20},   // Q4_1
+	6:  {32, 22},   // Q5_0
+	7:  {32, 24},   // Q5_1
+	8:  {32, 34},   // Q8_0
+	9:  {32, 36},   // Q8_1
+	10: {256, 84},  // Q2_K
+	11: {256, 110}, // Q3_K
+	12: {256, 144}, // Q4_K
+	13: {256, 176}, // Q5_K
+	14: {256, 210}, // Q6_K
+	15: {256, 292}, // Q8_K
+	16: {256, 66},  // IQ2_XXS
+	17: {256, 74},  // IQ2_XS
+	18: {256, 98},  // IQ3_XXS
+	19: {256, 50},  // IQ1_S
+	20: {32, 18},   // IQ4_NL
+	21: {256, 110}, // IQ3_S
+	22: {256, 82},  // IQ2_S
+	23: {256, 136}, // IQ4_XS
+	24: {1, 1},     // I8
+	25: {1, 2},     // I16
+	26: {1, 4},     // I32
+	27: {1, 8},     // I64
+	28: {1, 8},     // F64
+	29: {256, 56},  // IQ1_M
+	30: {1, 2},     // BF16
+	34: {256, 54},  // TQ1_0
+	35: {256, 66},  // TQ2_0
+	39: {32, 17},   // MXFP4
+}
+
+// ggufMetadata 一条 GGUF 元数据，数组只保留类型和长度
+type ggufMetadata struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// ggufTensor GGUF 张量信息
+type ggufTensor struct {
+	Name   string   `json:"name"`
+	Dims   []uint64 `json:"dims"`
+	Type   uint32   `json:"type"`
+	Offset uint64   `json:"offset"` // 相对于数据区起点的偏移
+}
+
+// ggufFile 解析后的 GGUF 文件头
+type ggufFile struct {
+	Version    uint32         `json:"version"`
+	Metadata   []ggufMetadata `json:"metadata"`
+	Tensors    []ggufTensor   `json:"-"`
+	DataOffset uint64         `json:"data_offset"` // 数据区在文件中的起始位置
+	FileSize   int64          `json:"file_size"`
+}
+
+// ggufReader 带有读取位置和边界检查的读取器
+type ggufReader struct {
+	r     *bufio.Reader
+	pos   uint64
+	size  uint64
+	depth int // 当前数组嵌套层数
+}
+
+func (g *ggufReader) read(v interface{}) error {
+	if err := binary.Read(g.r, binary.LittleEndian, v); err != nil {
+		return err
+	}
+	g.pos += uint64(binary.Size(v))
+	return nil
+}
+
+func (g *ggufReader) u32() (uint32, error) {
+	var v uint32
+	err := g.read(&v)
+	return v, err
+}
+
+func (g *ggufReader) u64() (uint64, error) {
+	var v uint64
+	err := g.read(&v)
+	return v, err
+}
+
+// count 读取一个数量，并检查它不会超出文件剩余大小，避免损坏文件导致超大分配
+func (g *ggufReader) count(minItemSize uint64) (uint64, error) {
+	n, err := g.u64()
+	if err != nil {
+		return 0, err
+	}
+	if minItemSize > 0 && n > (g.size-g.pos)/minItemSize {
+		return 0, fmt.Errorf("count %d at offset %d exceeds the file size", n, g.pos)
+	}
+	return n, nil
+}
+
+func (g *ggufReader) str() (string, error) {
+	n, err := g.count(1)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(g.r, buf); err != nil {
+		return "", err
+	}
+	g.pos += n
+	return string(buf), nil
+}
+
+// value 读取一个元数据值，数组只返回摘要
+func (g *ggufReader) value(typ uint32) (interface{}, error) {
+	switch typ {
+	case ggufTypeUint8:
+		var v uint8
+		err := g.read(&v)
+		return v, err
+	case ggufTypeInt8:
+		var v int8
+		err := g.read(&v)
+		return v, err
+	case ggufTypeUint16:
+		var v uint16
+		err := g.read(&v)
+		return v, err
+	case ggufTypeInt16:
+		var v int16
+		err := g.read(&v)
+		return v, err
+	case ggufTypeUint32:
+		var v uint32
+		err := g.read(&v)
+		return v, err
+	case ggufTypeInt32:
+		var v int32
+		err := g.read(&v)
+		return v, err
+	case ggufTypeFloat32:
+		var v float32
+		err := g.read(&v)
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return fmt.Sprint(v), err
+		}
+		return v, err
+	case ggufTypeBool:
+		var v uint8
+		err := g.read(&v)
+		return v != 0, err
+	case ggufTypeString:
+		return g.str()
+	case ggufTypeUint64:
+		var v uint64
+		err := g.read(&v)
+		return v, err
+	case ggufTypeInt64:
+		var v int64
+		err := g.read(&v)
+		return v, err
+	case ggufTypeFloat64:
+		var v float64
+		err := g.read(&v)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Sprint(v), err
+		}
+		return v, err
+	case ggufTypeArray:
+		elemType, err := g.u32()
+		if err != nil {
+			return nil, err
+		}
+		n, err := g.count(1)
+		if err != nil {
+			return nil, err
+		}
+		if g.depth >= ggufMaxArrayDepth {
+			return nil, fmt.Errorf("arrays nested more than %d levels at offset %d", ggufMaxArrayDepth, g.pos)
+		}
+		g.depth++
+		defer func() { g.depth-- }()
+		for i := uint64(0); i < n; i++ {
+			if _, err := g.value(elemType); err != nil {
+				return nil, err
+			}
+		}
+		return fmt.Sprintf("[%d items]", n), nil
+	}
+	return nil, fmt.Errorf("unknown metadata value type %d at offset %d", typ, g.pos)
+}
+
+// isGGUF 判断文件是否以 GGUF 文件头开始
+func isGGUF(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false
+	}
+	return string(magic) == ggufMagic
+}
+
+// readGGUF 解析 GGUF 文件头、元数据和张量信息
+func readGGUF(path string) (*ggufFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	g := &ggufReader{r: bufio.NewReaderSize(f, 1<<20), size: uint64(info.Size())}
+	out := &ggufFile{FileSize: info.Size()}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(g.r, magic); err != nil {
+		return nil, fmt.Errorf("failed to read GGUF header: %v", err)
+	}
+	g.pos = 4
+	if string(magic) != ggufMagic {
+		return nil, fmt.Errorf("not a GGUF file (magic %q)", magic)
+	}
+	if out.Version, err = g.u32(); err != nil {
+		return nil, fmt.Errorf("truncated GGUF header: %v", err)
+	}
+	if out.Version < 2 || out.Version > 3 {
+		return nil, fmt.Errorf("unsupported GGUF version %d", out.Version)
+	}
+
+	// 每个张量信息至少 8+4+8+4+8 字节，每条元数据至少 8+4 字节
+	tensorCount, err := g.count(32)
+	if err != nil {
+		return nil, fmt.Errorf("truncated GGUF header: %v", err)
+	}
+	kvCount, err := g.count(12)
+	if err != nil {
+		return nil, fmt.Errorf("truncated GGUF header: %v", err)
+	}
+
+	alignment := uint64(ggufDefaultAlignment)
+	for i := uint64(0); i < kvCount; i++ {
+		key, err := g.str()
+		if err != nil {
+			return nil, fmt.Errorf("truncated metadata: %v", err)
+		}
+		typ, err := g.u32()
+		if err != nil {
+			return nil, fmt.Errorf("truncated metadata: %v", err)
+		}
+		val, err := g.value(typ)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata %q: %v", key, err)
+		}
+		if key == "general.alignment" {
+			a, ok := val.(uint32)
+			if !ok || a == 0 || a&(a-1) != 0 {
+				return nil, fmt.Errorf("invalid general.alignment %v: must be a power of two", val)
+			}
+			alignment = uint64(a)
+		}
+		out.Metadata = append(out.Metadata, ggufMetadata{Key: key, Value: val})
+	}
+
+	for i := uint64(0); i < tensorCount; i++ {
+		var t ggufTensor
+		if t.Name, err = g.str(); err != nil {
+			return nil, fmt.Errorf("truncated tensor info: %v", err)
+		}
+		nDims, err := g.u32()
+		if err != nil {
+			return nil, fmt.Errorf("truncated tensor info: %v", err)
+		}
+		if nDims > 8 {
+			return nil, fmt.Errorf("tensor %q has invalid dimension count %d", t.Name, nDims)
+		}
+		t.Dims = make([]uint64, nDims)
+		for d := range t.Dims {
+			if t.Dims[d], err = g.u64(); err != nil {
+				return nil, fmt.Errorf("truncated tensor info: %v", err)
+			}
+		}
+		if t.Type, err = g.u32(); err != nil {
+			return nil, fmt.Errorf("truncated tensor info: %v", err)
+		}
+		if t.Offset, err = g.u64(); err != nil {
+			return nil, fmt.Errorf("truncated tensor info: %v", err)
+		}
+		out.Tensors = append(out.Tensors, t)
+	}
+
+	offset, carry := bits.Add64(g.pos, alignment-1, 0)
+	if carry != 0 {
+		return nil, fmt.Errorf("tensor data offset overflows")
+	}
+	out.DataOffset = offset / alignment * alignment
+	return out, nil
+}
+
+// tensorBytes 返回张量数据的字节数，未知类型返回 false。
+// 计算溢出时返回 math.MaxUint64，这样的张量必然超出文件范围。
+func (t ggufTensor) tensorBytes() (uint64, bool) {
+	sizes, ok := ggmlTypeSizes[t.Type]
+	if !ok {
+		return 0, false
+	}
+	n := uint64(1)
+	for _, d := range t.Dims {
+		hi, lo := bits.Mul64(n, d)
+		if hi != 0 {
+			return math.MaxUint64, true
+		}
+		n = lo
+	}
+	blocks := n / sizes[0]
+	if n%sizes[0] != 0 {
+		blocks++
+	}
+	hi, size := bits.Mul64(blocks, sizes[1])
+	if hi != 0 {
+		return math.MaxUint64, true
+	}
+	return size, true
+}
+
+// checkTensorBounds 检查所有张量数据都位于文件范围内，返回错误和警告
+func (g *ggufFile) checkTensorBounds() (issues, warnings []string) {
+	if g.DataOffset > uint64(g.FileSize) {
+		return []string{fmt.Sprintf("tensor data starts at %d but the file is only %d bytes", g.DataOffset, g.FileSize)}, nil
+	}
+	unknown := 0
+	for _, t := range g.Tensors {
+		size, ok := t.tensorBytes()
+		if !ok {
+			unknown++
+			continue
+		}
+		start, carry := bits.Add64(g.DataOffset, t.Offset, 0)
+		end, carry2 := bits.Add64(start, size, 0)
+		var issue string
+		switch {
+		case carry != 0 || carry2 != 0 || size == math.MaxUint64:
+			issue = fmt.Sprintf("tensor %q has an offset or size beyond any file size", t.Name)
+		case end > uint64(g.FileSize):
+			issue = fmt.Sprintf("tensor %q ends at byte %d, beyond the file size %d", t.Name, end, g.FileSize)
+		}
+		if issue != "" {
+			issues = append(issues, issue)
+			if len(issues) >= 5 {
+				issues = append(issues, "further tensor errors omitted")
+				break
+			}
+		}
+	}
+	if unknown > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d tensors use unknown types and were not size-checked", unknown))
+	}
+	return issues, warnings
+}
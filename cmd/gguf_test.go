@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ggufBuilder 构造测试用的 GGUF 文件头
+type ggufBuilder struct {
+	bytes.Buffer
+}
+
+func (b *ggufBuilder) u32(v uint32) *ggufBuilder {
+	binary.Write(b, binary.LittleEndian, v)
+	return b
+}
+
+func (b *ggufBuilder) u64(v uint64) *ggufBuilder {
+	binary.Write(b, binary.LittleEndian, v)
+	return b
+}
+
+func (b *ggufBuilder) str(s string) *ggufBuilder {
+	b.u64(uint64(len(s)))
+	b.WriteString(s)
+	return b
+}
+
+// header 写入文件头，version 为 3
+func (b *ggufBuilder) header(tensors, kvs uint64) *ggufBuilder {
+	b.WriteString(ggufMagic)
+	return b.u32(3).u64(tensors).u64(kvs)
+}
+
+// tensor 写入一个张量信息
+func (b *ggufBuilder) tensor(name string, typ uint32, offset uint64, dims ...uint64) *ggufBuilder {
+	b.str(name).u32(uint32(len(dims)))
+	for _, d := range dims {
+		b.u64(d)
+	}
+	return b.u32(typ).u64(offset)
+}
+
+// writeTemp 把内容写入临时文件
+func writeTemp(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// validGGUF 返回一个包含各类元数据和两个 F32 张量的完整文件
+func validGGUF() []byte {
+	b := &ggufBuilder{}
+	b.header(2, 6)
+	b.str("general.architecture").u32(ggufTypeString).str("llama")
+	b.str("general.alignment").u32(ggufTypeUint32).u32(64)
+	b.str("llama.context_length").u32(ggufTypeUint64).u64(4096)
+	b.str("general.flag").u32(ggufTypeBool)
+	b.WriteByte(1)
+	b.str("tokenizer.ggml.tokens").u32(ggufTypeArray).u32(ggufTypeString).u64(2).str("a").str("b")
+	b.str("general.scale").u32(ggufTypeFloat64).u64(math.Float64bits(math.Inf(1)))
+	b.tensor("token_embd.weight", 0, 0, 4, 2)
+	b.tensor("output.weight", 0, 32, 8)
+	// 对齐到 64 字节后是 64 字节的张量数据
+	for b.Len()%64 != 0 {
+		b.WriteByte(0)
+	}
+	b.Write(make([]byte, 64))
+	return b.Bytes()
+}
+
+func TestReadGGUF(t *testing.T) {
+	data := validGGUF()
+	g, err := readGGUF(writeTemp(t, data))
+	if err != nil {
+		t.Fatalf("readGGUF: %v", err)
+	}
+	if g.Version != 3 || len(g.Metadata) != 6 || len(g.Tensors) != 2 {
+		t.Fatalf("unexpected header: version %d, %d metadata, %d tensors", g.Version, len(g.Metadata), len(g.Tensors))
+	}
+	want := map[string]interface{}{
+		"general.architecture":  "llama",
+		"general.alignment":     uint32(64),
+		"llama.context_length":  uint64(4096),
+		"general.flag":          true,
+		"tokenizer.ggml.tokens": "[2 items]",
+		"general.scale":         "+Inf",
+	}
+	for _, m := range g.Metadata {
+		if want[m.Key] != m.Value {
+			t.Errorf("%s = %#v, want %#v", m.Key, m.Value, want[m.Key])
+		}
+	}
+	if g.DataOffset%64 != 0 || g.DataOffset+64 != uint64(len(data)) {
+		t.Errorf("data offset %d does not honour general.alignment (file size %d)", g.DataOffset, len(data))
+	}
+	if issues, warnings := g.checkTensorBounds(); len(issues) != 0 || len(warnings) != 0 {
+		t.Errorf("unexpected issues %v, warnings %v", issues, warnings)
+	}
+}
+
+// 文件在任意位置被截断时应返回错误而不是 panic
+func TestReadGGUFTruncated(t *testing.T) {
+	data := validGGUF()
+	g, err := readGGUF(writeTemp(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerEnd := int(g.DataOffset)
+	for n := 0; n < headerEnd; n++ {
+		g, err := readGGUF(writeTemp(t, data[:n]))
+		if err == nil && g.DataOffset <= uint64(n) {
+			t.Errorf("truncated at %d bytes: expected an error", n)
+		}
+	}
+
+	// 文件头完整但张量数据被截断
+	g, err = readGGUF(writeTemp(t, data[:len(data)-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues, _ := g.checkTensorBounds(); len(issues) == 0 {
+		t.Error("expected a tensor bounds issue for truncated tensor data")
+	}
+}
+
+func TestReadGGUFHostile(t *testing.T) {
+	tests := []struct {
+		name string
+		data func() []byte
+		want string
+	}{
+		{"bad magic", func() []byte { return []byte("GGML\x03\x00\x00\x00") }, "not a GGUF file"},
+		{"unsupported version", func() []byte {
+			b := &ggufBuilder{}
+			b.WriteString(ggufMagic)
+			return b.u32(99).u64(0).u64(0).Bytes()
+		}, "unsupported GGUF version"},
+		{"huge tensor count", func() []byte { return (&ggufBuilder{}).header(math.MaxUint64, 0).Bytes() }, "exceeds the file size"},
+		{"huge metadata count", func() []byte { return (&ggufBuilder{}).header(0, 1<<40).Bytes() }, "exceeds the file size"},
+		{"huge key length", func() []byte {
+			return (&ggufBuilder{}).header(0, 1).u64(math.MaxUint64).Bytes()
+		}, "exceeds the file size"},
+		{"huge string value", func() []byte {
+			b := (&ggufBuilder{}).header(0, 1)
+			return b.str("k").u32(ggufTypeString).u64(1 << 62).Bytes()
+		}, "exceeds the file size"},
+		{"huge array", func() []byte {
+			b := (&ggufBuilder{}).header(0, 1)
+			return b.str("k").u32(ggufTypeArray).u32(ggufTypeUint8).u64(math.MaxUint64).Bytes()
+		}, "exceeds the file size"},
+		{"array longer than the file", func() []byte {
+			b := (&ggufBuilder{}).header(0, 1)
+			b.str("k").u32(ggufTypeArray).u32(ggufTypeUint64).u64(16)
+			return b.u64(1).Bytes()
+		}, "invalid metadata"},
+		{"unknown value type", func() []byte {
+			b := (&ggufBuilder{}).header(0, 1)
+			return b.str("k").u32(99).u64(0).Bytes()
+		}, "unknown metadata value type 99"},
+		{"unknown array element type", func() []byte {
+			b := (&ggufBuilder{}).header(0, 1)
+			return b.str("k").u32(ggufTypeArray).u32(1000).u64(1).u64(0).Bytes()
+		}, "unknown metadata value type 1000"},
+		{"deeply nested arrays", func() []byte {
+			b := (&ggufBuilder{}).header(0, 1)
+			b.str("k").u32(ggufTypeArray)
+			for i := 0; i < 100; i++ {
+				b.u32(ggufTypeArray).u64(1)
+			}
+			return b.u32(ggufTypeUint8).u64(1).Bytes()
+		}, "nested more than"},
+		{"too many dimensions", func() []byte {
+			b := (&ggufBuilder{}).header(1, 0)
+			b.str("t").u32(1000)
+			return append(b.Bytes(), make([]byte, 64)...)
+		}, "invalid dimension count"},
+		{"alignment not a power of two", func() []byte {
+			b := (&ggufBuilder{}).header(0, 1)
+			return b.str("general.alignment").u32(ggufTypeUint32).u32(48).Bytes()
+		}, "must be a power of two"},
+		{"zero alignment", func() []byte {
+			b := (&ggufBuilder{}).header(0, 1)
+			return b.str("general.alignment").u32(ggufTypeUint32).u32(0).Bytes()
+		}, "must be a power of two"},
+	}
+	for _, tt := range tests {
+		_, err := readGGUF(writeTemp(t, tt.data()))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestCheckTensorBounds(t *testing.T) {
+	g := &ggufFile{DataOffset: 64, FileSize: 64 + 100, Tensors: []ggufTensor{
+		{Name: "ok", Type: 0, Dims: []uint64{25}},                                   // 100 字节
+		{Name: "past end", Type: 0, Offset: 4, Dims: []uint64{25}},                  // 超出 4 字节
+		{Name: "overflow", Type: 0, Dims: []uint64{math.MaxUint64, math.MaxUint64}}, // 元素数溢出
+		{Name: "unknown", Type: 9999, Dims: []uint64{1}},
+	}}
+	issues, warnings := g.checkTensorBounds()
+	if len(issues) != 2 || !strings.Contains(issues[0], "past end") || !strings.Contains(issues[1], "overflow") {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	// 各步计算溢出后的结果都可能落回文件范围内
+	g = &ggufFile{DataOffset: 64, FileSize: 64 + 100, Tensors: []ggufTensor{
+		{Name: "offset wraps", Type: 0, Offset: math.MaxUint64 - 10, Dims: []uint64{25}}, // 64+offset 回绕到 53
+		{Name: "size wraps", Type: 0, Dims: []uint64{1<<62 + 1}},                         // 元素数不溢出，字节数溢出
+		{Name: "end wraps", Type: 1, Offset: 1 << 63, Dims: []uint64{1<<62 + 25}},        // 64+offset+size 回绕到 114
+		{Name: "block rounding", Type: 2, Dims: []uint64{math.MaxUint64}},                // 按块向上取整时溢出
+	}}
+	issues, _ = g.checkTensorBounds()
+	if len(issues) != 4 {
+		t.Errorf("expected 4 overflow issues, got %v", issues)
+	}
+
+	g = &ggufFile{DataOffset: 128, FileSize: 64}
+	if issues, _ := g.checkTensorBounds(); len(issues) != 1 {
+		t.Errorf("expected an issue when the data offset is past the end, got %v", issues)
+	}
+}
+
+func TestIsGGUF(t *testing.T) {
+	if !isGGUF(writeTemp(t, validGGUF())) {
+		t.Error("valid file not detected as GGUF")
+	}
+	for _, data := range []string{"", "GG", "GGML0000", "PK\x03\x04"} {
+		if isGGUF(writeTemp(t, []byte(data))) {
+			t.Errorf("%q detected as GGUF", data)
+		}
+	}
+}
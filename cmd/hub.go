@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 各平台的默认 API 地址
+var hubEndpoints = map[string]string{
+	"huggingface": "https://huggingface.co",
+	"modelscope":  "https://www.modelscope.cn",
+}
+
+// 各平台的默认分支
+var defaultRevisions = map[string]string{
+	"huggingface": "main",
+	"modelscope":  "master",
+}
+
+var (
+	// 分支、标签或提交 ID
+	revisionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_./-]{0,127}$`)
+	// 完整的提交 SHA，固定到提交的模型不会过期
+	commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// validateRevision 校验 revision
+func validateRevision(revision string) error {
+	if !revisionPattern.MatchString(revision) || strings.Contains(revision, "..") {
+		return newError(errValidation, "invalid revision %q", revision)
+	}
+	return nil
+}
+
+// splitRevision 把 <repo>@<revision> 拆分为仓库 ID 和 revision
+func splitRevision(ref string) (string, string) {
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// hubEndpoint 返回平台的 API 地址，配置了镜像时使用镜像
+func hubEndpoint(platform string) string {
+	if cfg, err := loadConfig(); err == nil && cfg.Mirrors[platform] != "" {
+		return cfg.Mirrors[platform]
+	}
+	return hubEndpoints[platform]
+}
+
+// hubHTTPClient 返回访问模型平台 API 使用的 HTTP 客户端，按配置使用代理和 CA 证书
+func hubHTTPClient() (*http.Client, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport, err := cfg.transport()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Timeout: 30 * time.Second, Transport: transport}, nil
+}
+
+// hubToken 返回平台的访问令牌
+func hubToken(platform string) string {
+	if cfg, err := loadConfig(); err == nil {
+		return cfg.Tokens[platform]
+	}
+	return ""
+}
+
+// hubGet 请求平台 API 并解析 JSON 响应
+func hubGet(platform, path string, query url.Values, v interface{}) error {
+	return hubRequest(http.MethodGet, platform, path, query, nil, v)
+}
+
+// hubRequest 以 method 请求平台 API，body 不为空时以 JSON 发送，并解析 JSON 响应
+func hubRequest(method, platform, path string, query url.Values, body interface{}, v interface{}) error {
+	u := hubEndpoint(platform) + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if token := hubToken(platform); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client, err := hubHTTPClient()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", platform, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return newError(errNotFound, "%s: %s not found", platform, path)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return newError(errAuth, "%s: access to %s denied (status %d)", platform, path, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%s: %s returned status %d", platform, path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s: failed to parse response from %s: %v", platform, path, err)
+	}
+	return nil
+}
+
+// resolveRevision 把分支、标签或提交解析为提交 SHA
+func resolveRevision(platform, repoID, revision string) (string, error) {
+	if revision == "" {
+		revision = defaultRevisions[platform]
+	}
+
+	switch platform {
+	case "huggingface":
+		var info struct {
+			Sha string `json:"sha"`
+		}
+		if err := hubGet(platform, "/api/models/"+repoID+"/revision/"+url.PathEscape(revision), nil, &info); err != nil {
+			return "", err
+		}
+		if info.Sha == "" {
+			return "", fmt.Errorf("huggingface did not report a commit for %s@%s", repoID, revision)
+		}
+		return info.Sha, nil
+
+	case "modelscope":
+		// ModelScope 没有直接解析 revision 的接口，取该 revision 下最近一次提交的文件的 CommitId
+		files, err := listModelScopeFiles(repoID, revision)
+		if err != nil {
+			return "", err
+		}
+		var commit string
+		var latest int64
+		for _, f := range files {
+			if f.CommitID != "" && f.CommittedDate >= latest {
+				commit, latest = f.CommitID, f.CommittedDate
+			}
+		}
+		if commit == "" {
+			return "", fmt.Errorf("modelscope did not report a commit for %s@%s", repoID, revision)
+		}
+		return commit, nil
+	}
+	return "", newError(errValidation, "unsupported platform %q", platform)
+}
+
+// modelScopeFile ModelScope 文件列表中的一项
+type modelScopeFile struct {
+	Name          string `json:"Name"`
+	Path          string `json:"Path"`
+	Type          string `json:"Type"`
+	Size          int64  `json:"Size"`
+	Sha256        string `json:"Sha256"`
+	CommitID      string `json:"CommitId"`
+	CommittedDate int64  `json:"CommittedDate"`
+}
+
+// listModelScopeFiles 列出 ModelScope 仓库在某个 revision 下的所有文件
+func listModelScopeFiles(repoID, revision string) ([]modelScopeFile, error) {
+	var resp struct {
+		Code int `json:"Code"`
+		Data struct {
+			Files []modelScopeFile `json:"Files"`
+		} `json:"Data"`
+		Message string `json:"Message"`
+	}
+	query := url.Values{"Revision": {revision}, "Recursive": {"true"}}
+	if err := hubGet("modelscope", "/api/v1/models/"+repoID+"/repo/files", query, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 && resp.Code != 200 {
+		return nil, fmt.Errorf("modelscope: %s", resp.Message)
+	}
+	var files []modelScopeFile
+	for _, f := range resp.Data.Files {
+		if f.Type != "tree" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// downloadRevision 返回下载时使用的 revision。
+// Hugging Face 直接按提交 SHA 下载；ModelScope 的下载接口只接受分支或标签，按记录的 revision 下载。
+func downloadRevision(platform, revision, commit string) string {
+	if platform == "huggingface" && commit != "" {
+		return commit
+	}
+	return revision
+}
+
+// isPinned 判断 revision 是否直接固定到某个提交
+func isPinned(revision string) bool {
+	return commitPattern.MatchString(revision)
+}
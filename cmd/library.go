@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"oneinfer/client"
+
+	"github.com/gorilla/mux"
+)
+
+// libraryAddRequest POST /api/v1/models 的请求体
+type libraryAddRequest struct {
+	Platform    string `json:"platform"`     // huggingface、modelscope 或 local
+	Repo        string `json:"repo"`         // 远程仓库 ID，可带 @revision
+	FilePattern string `json:"file_pattern"` // 限制下载的文件模式
+	Quant       string `json:"quant"`        // 按量化类型选择 GGUF 文件
+	FitMemory   bool   `json:"fit_memory"`   // 选择能放入可用内存的最大 GGUF 文件
+	Name        string `json:"name"`         // 本地模型的名称
+	Path        string `json:"path"`         // 服务器上的模型文件或目录
+	Mode        string `json:"mode"`         // 本地模型的导入方式，默认 copy
+}
+
+// libraryAddEvent library.* 事件的数据，远程模型的下载进度见 download.* 事件
+type libraryAddEvent struct {
+	Request libraryAddRequest `json:"request"`
+	Model   *modelInfo        `json:"model,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// ggufInfoOutput GET /api/v1/models/{name}/gguf 的输出
+type ggufInfoOutput struct {
+	Path        string         `json:"path"`
+	Version     uint32         `json:"version"`
+	TensorCount int            `json:"tensor_count"`
+	FileSize    int64          `json:"file_size"`
+	Metadata    []ggufMetadata `json:"metadata"`
+}
+
+// writeJSON 以 JSON 返回 v
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// addLibraryModelHandler 添加模型。先校验参数，再创建后台任务下载远程模型或导入 import_roots 中的本地模型，
+// 返回 202 和任务，进度和结果通过事件流推送。
+func addLibraryModelHandler(w http.ResponseWriter, r *http.Request) {
+	var req libraryAddRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// 任务完成后发布 library.added 或 library.add_failed 事件
+	var job downloadJob
+	var err error
+	if req.Platform == "local" {
+		job, err = createImportJob(req)
+	} else {
+		job, err = createDownloadJob(req)
+	}
+	if err != nil {
+		requestLogger(r).Warn("failed to create download", "platform", req.Platform, "repo", req.Repo, "path", req.Path, "error", err)
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	w.Header().Set("Location", apiPrefix+"/downloads/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// getLibraryModelHandler 返回模型详情
+func getLibraryModelHandler(w http.ResponseWriter, r *http.Request) {
+	model, err := findModel(mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, model)
+}
+
+// updateLibraryModelHandler 修改模型的别名、标签和默认运行参数。
+// 请求体中出现的字段整体替换，defaults 为 null 时清除默认参数。
+func updateLibraryModelHandler(w http.ResponseWriter, r *http.Request) {
+	var req map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for key := range req {
+		if key != "aliases" && key != "labels" && key != "defaults" {
+			http.Error(w, fmt.Sprintf("Field %q cannot be changed", key), http.StatusBadRequest)
+			return
+		}
+	}
+
+	model, err := updateModel(mux.Vars(r)["name"], func(models []modelInfo, model *modelInfo) error {
+		if raw, ok := req["aliases"]; ok {
+			var aliases []string
+			if err := json.Unmarshal(raw, &aliases); err != nil {
+				return newError(errValidation, "aliases must be a list of strings")
+			}
+			for _, alias := range aliases {
+				if !aliasPattern.MatchString(alias) {
+					return newError(errValidation, "invalid alias %q: use letters, digits, '.', '_', ':' or '-'", alias)
+				}
+				for _, m := range models {
+					if m.Name != model.Name && m.matches(alias) || m.Name == alias {
+						return newError(errConflict, "alias '%s' is already used by model '%s'", alias, m.Name)
+					}
+				}
+			}
+			sort.Strings(aliases)
+			model.Aliases = removeDuplicates(aliases)
+		}
+		if raw, ok := req["labels"]; ok {
+			var labels []string
+			if err := json.Unmarshal(raw, &labels); err != nil {
+				return newError(errValidation, "labels must be a list of strings")
+			}
+			for _, label := range labels {
+				if !labelPattern.MatchString(label) {
+					return newError(errValidation, "invalid label %q: use letters, digits, '.', '_' or '-'", label)
+				}
+			}
+			sort.Strings(labels)
+			model.Labels = removeDuplicates(labels)
+		}
+		if raw, ok := req["defaults"]; ok {
+			var defaults *runDefaults
+			if err := json.Unmarshal(raw, &defaults); err != nil {
+				return newError(errValidation, "defaults must be an object with host and port")
+			}
+			if defaults != nil && (defaults.Port < 0 || defaults.Port > 65535) {
+				return newError(errValidation, "invalid port %d", defaults.Port)
+			}
+			if defaults != nil && *defaults == (runDefaults{}) {
+				defaults = nil
+			}
+			model.Defaults = defaults
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	requestLogger(r).Info("model updated", "name", model.Name)
+	writeJSON(w, http.StatusOK, model)
+}
+
+// deleteLibraryModelHandler 删除模型，正在运行的模型需要先停止
+func deleteLibraryModelHandler(w http.ResponseWriter, r *http.Request) {
+	model, err := findModel(mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+
+	modelMux.Lock()
+	running := make([]client.Instance, 0, len(models))
+	for _, mp := range models {
+		running = append(running, client.Instance{ID: mp.ID, Name: mp.Name, Model: mp.Model})
+	}
+	modelMux.Unlock()
+	if err := checkModelStopped(model, running); err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+
+	if err := removeModel(model.Name); err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	requestLogger(r).Info("model removed", "name", model.Name)
+	writeJSON(w, http.StatusOK, removeOutput{Name: model.Name, Status: "removed"})
+}
+
+// libraryGGUFHandler 返回模型 GGUF 文件头中的元数据
+func libraryGGUFHandler(w http.ResponseWriter, r *http.Request) {
+	model, err := findModel(mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	if !isGGUF(model.Path) {
+		http.Error(w, fmt.Sprintf("Model '%s' is not a GGUF file", model.Name), http.StatusBadRequest)
+		return
+	}
+	gguf, err := readGGUF(model.Path)
+	if err != nil {
+		http.Error(w, "Failed to read GGUF metadata: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusOK, ggufInfoOutput{
+		Path:        model.Path,
+		Version:     gguf.Version,
+		TensorCount: len(gguf.Tensors),
+		FileSize:    gguf.FileSize,
+		Metadata:    gguf.Metadata,
+	})
+}
+
+// removeDuplicates 删除已排序列表中的重复项，列表为空时返回 nil
+func removeDuplicates(sorted []string) []string {
+	var out []string
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// serveLibraryPage 返回模型库管理页面
+func serveLibraryPage(w http.ResponseWriter, r *http.Request) {
+	data, err := staticFiles.ReadFile("static/library.html")
+	if err != nil {
+		http.Error(w, "Could not load library.html", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(data)
+}
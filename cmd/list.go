@@ -1,74 +1,138 @@
 package cmd
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+// modelInfo models.json 中的一条模型记录
+type modelInfo struct {
+	Name        string       `json:"name"`
+	Platform    string       `json:"platform"`
+	Source      string       `json:"source,omitempty"`       // 远程仓库 ID 或本地原始路径
+	FilePattern string       `json:"file_pattern,omitempty"` // 下载时使用的文件模式
+	Revision    string       `json:"revision,omitempty"`     // 远程模型的分支、标签或提交
+	Commit      string       `json:"commit,omitempty"`       // 下载时 revision 对应的提交 SHA
+	Path        string       `json:"path"`                   // 传给推理后端的模型路径
+	Mode        string       `json:"mode,omitempty"`         // 本地模型的导入方式
+	Mmproj      string       `json:"mmproj,omitempty"`       // 多模态模型的 mmproj 文件
+	Aliases     []string     `json:"aliases,omitempty"`      // 可以代替模型名使用的别名
+	Labels      []string     `json:"labels,omitempty"`       // 自由标签，如 chat、embedding、prod
+	Files       []modelFile  `json:"files,omitempty"`        // 文件清单，旧版本注册的模型没有
+	Defaults    *runDefaults `json:"defaults,omitempty"`     // run 命令的默认参数
+	Size        int64        `json:"size"`                   // 所有文件的总大小
+	AddedDate   string       `json:"added_date,omitempty"`
+}
+
+// runDefaults 模型的默认运行参数，run 命令未指定对应参数时使用
+type runDefaults struct {
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+}
+
+// modelListOutput list 命令的输出结构
+type modelListOutput struct {
+	Models []modelInfo `json:"models"`
+}
+
 var modelListCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls"},
 	Short:   "List all added models",
-	Run: func(cmd *cobra.Command, args []string) {
-		err := listModels()
+	Args:    validArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		models, err := listModels()
 		if err != nil {
-			fmt.Println("Error:", err)
+			return err
 		}
+
+		// 按标签过滤
+		if labels, _ := cmd.Flags().GetStringSlice("label"); len(labels) > 0 {
+			filtered := []modelInfo{}
+			for _, model := range models {
+				if model.hasLabels(labels) {
+					filtered = append(filtered, model)
+				}
+			}
+			models = filtered
+		}
+
+		return printResult(modelListOutput{Models: models}, func(w io.Writer) {
+			if len(models) == 0 {
+				io.WriteString(w, "No models found.\n")
+				return
+			}
+			t := &table{
+				headers:     []string{"MODEL NAME", "PLATFORM", "SIZE", "PATH"},
+				wideHeaders: []string{"ALIASES", "LABELS", "SOURCE", "FILES", "ADDED"},
+			}
+			for _, model := range models {
+				t.addRow(
+					[]string{model.Name, model.Platform, formatBytes(model.Size), model.Path},
+					strings.Join(model.Aliases, ","), strings.Join(model.Labels, ","),
+					model.Source, strconv.Itoa(len(model.Files)), model.AddedDate,
+				)
+			}
+			t.write(w)
+		})
 	},
 }
 
 func init() {
+	modelListCmd.Flags().StringSliceP("label", "l", nil, "Only list models with this label (repeatable)")
+	modelListCmd.RegisterFlagCompletionFunc("label", completeLabels)
 	rootCmd.AddCommand(modelListCmd)
 }
 
-// listModels 列出所有保存的模型
-func listModels() error {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return err
+// matches 判断 name 是否为模型名或其别名
+func (m modelInfo) matches(name string) bool {
+	if m.Name == name {
+		return true
 	}
-
-	modelDir := filepath.Join(homeDir, ".oneinfer", "models")
-	metaPath := filepath.Join(modelDir, "models.json")
-
-	// 读取现有的 models.json
-	if _, err := os.Stat(metaPath); err != nil {
-		if os.IsNotExist(err) {
-			fmt.Println("No models found.")
-			return nil
+	for _, alias := range m.Aliases {
+		if alias == name {
+			return true
 		}
-		return err
 	}
+	return false
+}
 
-	// 解析 models.json 文件
-	var models []map[string]string
-	file, err := os.ReadFile(metaPath)
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(file, &models)
-	if err != nil {
-		return err
+// hasLabels 判断模型是否带有所有指定的标签
+func (m modelInfo) hasLabels(labels []string) bool {
+	for _, want := range labels {
+		found := false
+		for _, label := range m.Labels {
+			if label == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
 	}
+	return true
+}
 
-	// 输出模型列表
-	if len(models) == 0 {
-		fmt.Println("No models found.")
-		return nil
+// listModels 列出所有保存的模型
+func listModels() ([]modelInfo, error) {
+	return loadRegistry()
+}
+
+// findModel 按名称或别名查找已保存的模型
+func findModel(name string) (modelInfo, error) {
+	models, err := listModels()
+	if err != nil {
+		return modelInfo{}, err
 	}
 
-	// 打印模型信息
-	fmt.Printf("%-30s %-20s %-50s\n", "Model Name", "Platform", "Path")
-	fmt.Println(strings.Repeat("-", 100))
 	for _, model := range models {
-		// 打印每个模型的名称、平台和路径
-		fmt.Printf("%-30s %-20s %-50s\n", model["name"], model["platform"], model["path"])
+		if model.matches(name) {
+			return model, nil
+		}
 	}
-
-	return nil
+	return modelInfo{}, newError(errNotFound, "model '%s' not found", name)
 }
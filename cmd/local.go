@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 本地模型的导入方式
+const (
+	importCopy     = "copy"
+	importHardlink = "hardlink"
+	importSymlink  = "symlink"
+	importInPlace  = "inplace"
+)
+
+// importModes 支持的导入方式
+var importModes = []string{importCopy, importHardlink, importSymlink, importInPlace}
+
+var (
+	// 本地模型名只允许字母、数字以及 . _ -
+	localNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,127}$`)
+	// 分片 GGUF 文件名，如 model-00001-of-00003.gguf
+	shardPattern = regexp.MustCompile(`-(\d{5})-of-(\d{5})\.gguf$`)
+)
+
+// validateImportMode 校验导入方式
+func validateImportMode(mode string) error {
+	for _, m := range importModes {
+		if m == mode {
+			return nil
+		}
+	}
+	return newError(errValidation, "invalid import mode %q: expected one of %s", mode, strings.Join(importModes, ", "))
+}
+
+// validateLocalName 校验本地模型名，模型名会作为 models 下的目录名
+func validateLocalName(name string) error {
+	if !localNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return newError(errValidation, "invalid model name %q: use letters, digits, '.', '_' or '-'", name)
+	}
+	return nil
+}
+
+// collectModelFiles 返回 root 下的所有文件（相对路径），root 为文件时只返回该文件
+func collectModelFiles(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{filepath.Base(root)}, nil
+	}
+
+	var files []string
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// 跳过隐藏文件和目录（如 .git、.cache）
+		if path != root && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// 指向文件的符号链接（如 Hugging Face 缓存中的快照）按其目标文件处理
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Stat(path); err == nil {
+				info = target
+			}
+		}
+		if info.Mode().IsRegular() {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, newError(errValidation, "no model files found in %q", root)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// resolveImportPath 检查通过 API 导入的本地路径位于 import_roots 中，返回解析符号链接后的路径。
+// 目录中的文件（包括符号链接指向的目标）也必须位于 import_roots 中。
+// 未配置 import_roots 时不允许通过 API 导入本地路径。
+func resolveImportPath(roots []string, localPath string) (string, error) {
+	if len(roots) == 0 {
+		return "", newError(errAuth, "importing server paths through the API is disabled: set import_roots on the server with 'oneinfer config set import_roots <dir>[,<dir>...]'")
+	}
+	if !filepath.IsAbs(localPath) {
+		return "", newError(errValidation, "invalid local model path %q: expected an absolute path", localPath)
+	}
+	resolved, err := filepath.EvalSymlinks(localPath)
+	if err != nil {
+		return "", wrapError(errValidation, err, "invalid local model path %q", localPath)
+	}
+	var resolvedRoots []string
+	for _, root := range roots {
+		if r, err := filepath.EvalSymlinks(root); err == nil {
+			resolvedRoots = append(resolvedRoots, r)
+		}
+	}
+	outside := newError(errAuth, "path %q is outside the import roots (%s)", localPath, strings.Join(roots, ", "))
+	if !withinRoots(resolvedRoots, resolved) {
+		return "", outside
+	}
+
+	files, err := collectModelFiles(resolved)
+	if err != nil {
+		return "", err
+	}
+	dir := resolved
+	if info, err := os.Stat(resolved); err == nil && !info.IsDir() {
+		dir = filepath.Dir(resolved)
+	}
+	for _, f := range files {
+		target, err := filepath.EvalSymlinks(filepath.Join(dir, f))
+		if err != nil || !withinRoots(resolvedRoots, target) {
+			return "", outside
+		}
+	}
+	return resolved, nil
+}
+
+// withinRoots 判断 path 是否为某个根目录或位于其中，路径都已解析过符号链接
+func withinRoots(roots []string, path string) bool {
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// primaryModelFiles 从文件列表中选出主模型文件和 mmproj 文件
+// 分片 GGUF 取第一个分片，否则取第一个非 mmproj 的 GGUF 文件；没有 GGUF 时返回空
+func primaryModelFiles(files []string) (model, mmproj string) {
+	for _, f := range files {
+		base := strings.ToLower(filepath.Base(f))
+		if !strings.HasSuffix(base, ".gguf") {
+			continue
+		}
+		if strings.Contains(base, "mmproj") {
+			if mmproj == "" {
+				mmproj = f
+			}
+			continue
+		}
+		if m := shardPattern.FindStringSubmatch(base); m != nil && m[1] != "00001" {
+			continue
+		}
+		if model == "" {
+			model = f
+		}
+	}
+	return model, mmproj
+}
+
+// importLocalModel 按指定方式导入本地文件或目录并注册到 models.json，进度输出到 log
+func importLocalModel(name, localPath, mode string, log *slog.Logger) (modelInfo, error) {
+	if err := validateLocalName(name); err != nil {
+		return modelInfo{}, err
+	}
+	if err := validateImportMode(mode); err != nil {
+		return modelInfo{}, err
+	}
+	if localPath == "" {
+		return modelInfo{}, newError(errValidation, "a local model path is required: oneinfer add %s local <path>", name)
+	}
+	if err := ensureNameAvailable(name); err != nil {
+		return modelInfo{}, err
+	}
+
+	srcPath, err := filepath.Abs(localPath)
+	if err != nil {
+		return modelInfo{}, err
+	}
+	srcInfo, err := os.Stat(srcPath)
+	if err != nil {
+		return modelInfo{}, wrapError(errValidation, err, "invalid local model path %q", localPath)
+	}
+	files, err := collectModelFiles(srcPath)
+	if err != nil {
+		return modelInfo{}, err
+	}
+
+	// 单个文件时相对路径是文件名，根目录是其所在目录
+	root := srcPath
+	if !srcInfo.IsDir() {
+		root = filepath.Dir(srcPath)
+	}
+	lease, err := newBlobLease()
+	if err != nil {
+		return modelInfo{}, err
+	}
+	defer lease.release()
+	manifest, err := ingestModelFiles(root, files, mode, lease, log)
+	if err != nil {
+		return modelInfo{}, err
+	}
+
+	return registerModel(modelInfo{
+		Name:     name,
+		Platform: "local",
+		Source:   srcPath,
+		Mode:     mode,
+		Files:    manifest,
+	})
+}
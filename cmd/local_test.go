@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveImportPath(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "models")
+	outside := filepath.Join(base, "secrets")
+	for _, dir := range []string{filepath.Join(root, "qwen"), filepath.Join(root, "linked"), outside} {
+		os.MkdirAll(dir, 0755)
+	}
+	os.WriteFile(filepath.Join(root, "qwen", "model.gguf"), []byte("GGUF"), 0644)
+	os.WriteFile(filepath.Join(outside, "id_rsa"), []byte("key"), 0600)
+	// 名称以根目录为前缀的相邻目录
+	os.MkdirAll(root+"-backup", 0755)
+	os.WriteFile(filepath.Join(root+"-backup", "model.gguf"), []byte("GGUF"), 0644)
+	// 指向根目录之外的符号链接
+	os.Symlink(outside, filepath.Join(root, "escape"))
+	os.Symlink(filepath.Join(outside, "id_rsa"), filepath.Join(root, "linked", "model.gguf"))
+	roots := []string{root}
+
+	got, err := resolveImportPath(roots, filepath.Join(root, "qwen"))
+	if err != nil || got != filepath.Join(root, "qwen") {
+		t.Errorf("path inside the root: %q, %v", got, err)
+	}
+	if _, err := resolveImportPath(roots, filepath.Join(root, "qwen", "model.gguf")); err != nil {
+		t.Errorf("file inside the root: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		kind errorKind
+	}{
+		{"outside", filepath.Join(outside, "id_rsa"), errAuth},
+		{"dot-dot", filepath.Join(root, "..", "secrets"), errAuth},
+		{"prefix", root + "-backup", errAuth},
+		{"symlinked dir", filepath.Join(root, "escape"), errAuth},
+		{"symlinked file in dir", filepath.Join(root, "linked"), errAuth},
+		{"relative", "models/qwen", errValidation},
+		{"missing", filepath.Join(root, "missing"), errValidation},
+	}
+	for _, tt := range tests {
+		if _, err := resolveImportPath(roots, tt.path); kindOf(err) != tt.kind {
+			t.Errorf("%s: error = %v, want kind %s", tt.name, err, tt.kind)
+		}
+	}
+
+	if _, err := resolveImportPath(nil, filepath.Join(root, "qwen")); kindOf(err) != errAuth {
+		t.Errorf("no import roots: error = %v, want an auth error", err)
+	}
+}
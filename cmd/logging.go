@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// serve 日志的默认设置
+const (
+	defaultLogMaxSize    = 10 // MB
+	defaultLogMaxBackups = 5
+)
+
+// logOptions serve 命令的日志参数
+type logOptions struct {
+	Level      string
+	Format     string
+	File       string
+	MaxSize    int
+	MaxBackups int
+}
+
+// requestIDKey 请求 ID 在 context 中的键
+type requestIDKey struct{}
+
+// setupLogging 根据参数创建 slog 日志并设为默认日志。
+// 日志写入按大小轮转的文件，标准错误是终端时同时输出到终端。
+func setupLogging(opts logOptions) (io.Closer, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+		return nil, newError(errValidation, "invalid log level %q: expected debug, info, warn or error", opts.Level)
+	}
+	if opts.Format != "text" && opts.Format != "json" {
+		return nil, newError(errValidation, "invalid log format %q: expected text or json", opts.Format)
+	}
+	if opts.MaxSize <= 0 || opts.MaxBackups < 0 {
+		return nil, newError(errValidation, "--log-max-size must be positive and --log-max-backups must not be negative")
+	}
+
+	path := opts.File
+	if path == "" {
+		dir, err := oneinferDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, "logs", "serve.log")
+	}
+	file, err := openRotatingFile(path, int64(opts.MaxSize)<<20, opts.MaxBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	var w io.Writer = file
+	if isTerminal(os.Stderr) {
+		w = io.MultiWriter(file, os.Stderr)
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewTextHandler(w, handlerOpts)
+	if opts.Format == "json" {
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	}
+	slog.SetDefault(slog.New(handler))
+	return file, nil
+}
+
+// rotatingFile 按大小轮转的日志文件，写满后依次重命名为 .1、.2 ...，只保留 maxBackups 个
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// openRotatingFile 以追加方式打开日志文件
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+// Write 写入一条日志，超过大小限制时先轮转
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate 关闭当前文件，把旧文件依次后移，再打开新文件
+func (r *rotatingFile) rotate() error {
+	r.file.Close()
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxBackups))
+	for i := r.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if r.maxBackups > 0 {
+		os.Rename(r.path, r.path+".1")
+	} else {
+		os.Remove(r.path)
+	}
+	return r.open()
+}
+
+// Close 关闭日志文件
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// newRequestID 生成随机的请求 ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestLogger 返回带有请求 ID 的日志
+func requestLogger(r *http.Request) *slog.Logger {
+	if id, ok := r.Context().Value(requestIDKey{}).(string); ok {
+		return slog.With("request_id", id)
+	}
+	return slog.Default()
+}
+
+// accessLogMiddleware 为每个请求分配请求 ID（沿用客户端传入的 X-Request-ID），并记录访问日志
+func accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 64 {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := routeTemplate(r)
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(r.Context(), level, "request",
+			"request_id", id,
+			"method", r.Method,
+			"route", route,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"remote", r.RemoteAddr,
+		)
+	})
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"oneinfer/client"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/cobra"
+)
+
+var logsCmd = &cobra.Command{
+	Use:               "logs <model_id|model_name>",
+	Short:             "Show the output of a running model",
+	Args:              validArgs(cobra.ExactArgs(1)),
+	ValidArgsFunction: completeInstances,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		modelID, err := resolveInstanceID(args[0])
+		if err != nil {
+			return err
+		}
+		follow, _ := cmd.Flags().GetBool("follow")
+		tail, _ := cmd.Flags().GetInt("tail")
+
+		return streamModelLogs(modelID, follow, tail, os.Stdout)
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep streaming new output until the model stops")
+	logsCmd.Flags().IntP("tail", "n", 0, "Only show the last N lines (0 shows everything)")
+	rootCmd.AddCommand(logsCmd)
+}
+
+// streamModelLogs 从 serve 读取模型日志并写到 w
+func streamModelLogs(modelID int, follow bool, tail int, w io.Writer) error {
+	logs, err := apiClient().StreamLogs(context.Background(), modelID, client.LogOptions{Follow: follow, Tail: tail})
+	if err != nil {
+		return clientError(err)
+	}
+	defer logs.Close()
+
+	_, err = io.Copy(w, logs)
+	return err
+}
+
+// instanceLogDir 返回模型进程日志目录
+func instanceLogDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".oneinfer", "logs", "instances"), nil
+}
+
+// createInstanceLog 创建一个新的模型进程日志文件，启动后再按实例 ID 重命名
+func createInstanceLog() (*os.File, error) {
+	dir, err := instanceLogDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(dir, "starting-*.log")
+}
+
+// tailLines 返回 data 的最后 n 行
+func tailLines(data []byte, n int) []byte {
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// 读取模型进程日志，follow=true 时持续输出直到进程停止或客户端断开
+func modelLogsHandler(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid process ID", http.StatusBadRequest)
+		return
+	}
+
+	modelMux.Lock()
+	modelProcess, exists := models[pid]
+	modelMux.Unlock()
+	if !exists {
+		http.Error(w, "Process not found", http.StatusNotFound)
+		return
+	}
+
+	file, err := os.Open(modelProcess.LogPath)
+	if err != nil {
+		http.Error(w, "Failed to open model log", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if tail, _ := strconv.Atoi(r.URL.Query().Get("tail")); tail > 0 {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, "Failed to read model log", http.StatusInternalServerError)
+			return
+		}
+		w.Write(tailLines(data, tail))
+	} else {
+		io.Copy(w, bufio.NewReader(file))
+	}
+
+	if r.URL.Query().Get("follow") != "true" {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		io.Copy(w, file)
+
+		// 进程已停止时输出剩余内容后结束
+		modelMux.Lock()
+		_, running := models[pid]
+		modelMux.Unlock()
+		if !running {
+			io.Copy(w, file)
+			return
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Prometheus 文本格式的指标，只实现 serve 需要的计数器和直方图
+
+// 直方图的默认分桶（秒）
+var (
+	requestBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	startBuckets   = []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300}
+)
+
+// counterVec 带标签的计数器
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+	series map[string][]string
+}
+
+// histogramVec 带标签的直方图
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogram
+	series map[string][]string
+}
+
+// histogram 一组标签对应的直方图数据，counts[i] 为不大于 buckets[i] 的观测数
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: map[string]float64{}, series: map[string][]string{}}
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, values: map[string]*histogram{}, series: map[string][]string{}}
+}
+
+// add 给标签值对应的计数器增加 v
+func (c *counterVec) add(v float64, values ...string) {
+	key := strings.Join(values, "\xff")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.series[key]; !ok {
+		c.series[key] = values
+	}
+	c.values[key] += v
+}
+
+// inc 给标签值对应的计数器加一
+func (c *counterVec) inc(values ...string) {
+	c.add(1, values...)
+}
+
+// observe 记录一次观测值
+func (h *histogramVec) observe(v float64, values ...string) {
+	key := strings.Join(values, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	data, ok := h.values[key]
+	if !ok {
+		data = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = data
+		h.series[key] = values
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			data.counts[i]++
+		}
+	}
+	data.count++
+	data.sum += v
+}
+
+// writeTo 以 Prometheus 文本格式输出计数器
+func (c *counterVec) writeTo(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeMetricHeader(w, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.series) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, c.series[key]), formatValue(c.values[key]))
+	}
+}
+
+// writeTo 以 Prometheus 文本格式输出直方图
+func (h *histogramVec) writeTo(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeMetricHeader(w, h.name, h.help, "histogram")
+	labels := append(append([]string{}, h.labels...), "le")
+	for _, key := range sortedKeys(h.series) {
+		data := h.values[key]
+		values := h.series[key]
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labels, append(append([]string{}, values...), formatValue(b))), data.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labels, append(append([]string{}, values...), "+Inf")), data.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, values), formatValue(data.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, values), data.count)
+	}
+}
+
+// writeMetricHeader 输出指标的 HELP 和 TYPE 行
+func writeMetricHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// formatLabels 把标签格式化为 {k="v",...}
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		v := ""
+		if i < len(values) {
+			v = values[i]
+		}
+		v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+		parts[i] = name + `="` + v + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatValue 格式化指标值
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sortedKeys 返回排序后的 map 键，保证输出顺序稳定
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// serve 进程的指标
+var (
+	apiRequests = newCounterVec("oneinfer_http_requests_total",
+		"API requests handled by the serve process.", "method", "route", "code")
+	apiRequestDuration = newHistogramVec("oneinfer_http_request_duration_seconds",
+		"Latency of API requests handled by the serve process.", requestBuckets, "method", "route")
+	modelRestarts = newCounterVec("oneinfer_model_restarts_total",
+		"Automatic restarts of model processes after they exited unexpectedly.", "model")
+	modelStartDuration = newHistogramVec("oneinfer_model_start_duration_seconds",
+		"Time from spawning a model process until its health endpoint reports ready.", startBuckets, "model")
+)
+
+// statusRecorder 记录响应状态码和字节数，并保留 Flusher 以支持流式响应
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// metricsMiddleware 按路由模板统计 API 请求次数和延迟
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := routeTemplate(r)
+		apiRequests.inc(r.Method, route, strconv.Itoa(rec.status))
+		apiRequestDuration.observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
+
+// routeTemplate 返回请求匹配的路由模板，如 /models/{id}，避免按 ID 产生大量标签值
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if tpl, err := current.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
+
+// metricsHandler 以 Prometheus 文本格式输出指标
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	modelMux.Lock()
+	running := 0
+	procs := make([]ModelProcess, 0, len(models))
+	for _, mp := range models {
+		if mp.Status == statusRunning {
+			running++
+		}
+		procs = append(procs, *mp)
+	}
+	modelMux.Unlock()
+	sort.Slice(procs, func(i, j int) bool { return procs[i].ID < procs[j].ID })
+
+	writeMetricHeader(w, "oneinfer_models_running", "Model processes that are running and ready.", "gauge")
+	fmt.Fprintf(w, "oneinfer_models_running %d\n", running)
+
+	// 每个模型进程组的 CPU 和内存，取 sampleInstances 最近一次从 /proc 采样的结果
+	writeMetricHeader(w, "oneinfer_model_cpu_seconds_total", "CPU time used by the model process group.", "counter")
+	for _, mp := range procs {
+		if mp.stats != nil && !mp.exited {
+			fmt.Fprintf(w, "oneinfer_model_cpu_seconds_total%s %s\n", formatLabels([]string{"id", "model"}, []string{strconv.Itoa(mp.ID), mp.displayName()}), formatValue(mp.stats.CPUSeconds))
+		}
+	}
+	writeMetricHeader(w, "oneinfer_model_resident_memory_bytes", "Resident memory of the model process group.", "gauge")
+	for _, mp := range procs {
+		if mp.stats != nil && !mp.exited {
+			fmt.Fprintf(w, "oneinfer_model_resident_memory_bytes%s %d\n", formatLabels([]string{"id", "model"}, []string{strconv.Itoa(mp.ID), mp.displayName()}), mp.stats.RSS)
+		}
+	}
+
+	modelRestarts.writeTo(w)
+	modelStartDuration.writeTo(w)
+	apiRequests.writeTo(w)
+	apiRequestDuration.writeTo(w)
+	gatewayRequests.writeTo(w)
+	gatewayRequestDuration.writeTo(w)
+	gatewayTimeToFirstToken.writeTo(w)
+	gatewayTokens.writeTo(w)
+}
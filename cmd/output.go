@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 支持的输出格式
+const (
+	outputTable = "table"
+	outputWide  = "wide"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// outputFormat 由全局 --output 参数设置
+var outputFormat = outputTable
+
+// validateOutputFormat 校验 --output 参数
+func validateOutputFormat() error {
+	switch outputFormat {
+	case outputTable, outputWide, outputJSON, outputYAML:
+		return nil
+	}
+	return newError(errValidation, "invalid output format %q: expected table, wide, json or yaml", outputFormat)
+}
+
+// machineOutput 判断当前是否为机器可读的输出格式
+func machineOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// infoOut 返回进度等提示信息的输出位置，机器可读输出时写到 stderr，避免污染 stdout
+func infoOut() io.Writer {
+	if machineOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// infof 输出导入、下载过程中的提示。log 为空时写到终端，serve 的后台任务传入带 job 属性的日志。
+func infof(log *slog.Logger, format string, args ...interface{}) {
+	if log == nil {
+		fmt.Fprintf(infoOut(), format+"\n", args...)
+		return
+	}
+	log.Info(fmt.Sprintf(format, args...))
+}
+
+// warnf 输出警告，log 为空时以 Warning: 开头写到 stderr
+func warnf(log *slog.Logger, format string, args ...interface{}) {
+	if log == nil {
+		fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+		return
+	}
+	log.Warn(fmt.Sprintf(format, args...))
+}
+
+// table 表格输出，wide 模式下会额外显示 wideHeaders 对应的列
+type table struct {
+	headers     []string
+	wideHeaders []string
+	rows        [][]string
+	wideRows    [][]string
+}
+
+// addRow 添加一行，wide 为仅在 wide 模式下显示的列
+func (t *table) addRow(row []string, wide ...string) {
+	t.rows = append(t.rows, row)
+	t.wideRows = append(t.wideRows, wide)
+}
+
+// write 按当前输出格式打印表格
+func (t *table) write(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	headers := t.headers
+	if outputFormat == outputWide {
+		headers = append(append([]string{}, headers...), t.wideHeaders...)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for i, row := range t.rows {
+		if outputFormat == outputWide {
+			row = append(append([]string{}, row...), t.wideRows[i]...)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+// printResult 按 --output 输出结果：json/yaml 直接序列化 v，table/wide 调用 printTable
+func printResult(v interface{}, printTable func(w io.Writer)) error {
+	switch outputFormat {
+	case outputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		data, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	default:
+		printTable(os.Stdout)
+		return nil
+	}
+}
+
+// errorBody 机器可读的错误对象
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code     errorKind `json:"code"`
+	ExitCode int       `json:"exit_code"`
+	Message  string    `json:"message"`
+}
+
+// printError 按 --output 输出错误，机器可读格式下输出错误对象
+func printError(err error) {
+	body := errorBody{Error: errorDetail{Code: kindOf(err), ExitCode: ExitCode(err), Message: err.Error()}}
+	switch outputFormat {
+	case outputJSON:
+		enc := json.NewEncoder(os.Stderr)
+		enc.SetIndent("", "  ")
+		enc.Encode(body)
+	case outputYAML:
+		data, _ := toYAML(body)
+		os.Stderr.Write(data)
+	default:
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+}
+
+// toYAML 把 v 以 JSON 序列化后转换为 YAML，字段顺序与 JSON 保持一致
+func toYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := decodeYAMLNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeYAMLNode 把 JSON 解析为 YAML 节点，保留对象的键顺序
+func decodeYAMLNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		kind := yaml.SequenceNode
+		if t == '{' {
+			kind = yaml.MappingNode
+		}
+		node := &yaml.Node{Kind: kind}
+		for dec.More() {
+			if kind == yaml.MappingNode {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, err := yamlScalar(keyTok)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, key)
+			}
+			val, err := decodeYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, val)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		// 空对象和空列表写成 {} 和 []
+		if len(node.Content) == 0 {
+			node.Style = yaml.FlowStyle
+		}
+		return node, nil
+	}
+	return yamlScalar(tok)
+}
+
+// yamlScalar 把 JSON 标量转换为 YAML 节点。数字保持 JSON 中的写法，
+// 字符串由编码器决定是否加引号，如 yes、null、"-1" 等会被加引号以免被解析为其他类型
+func yamlScalar(tok json.Token) (*yaml.Node, error) {
+	if n, ok := tok.(json.Number); ok {
+		tag := "!!int"
+		if strings.ContainsAny(n.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: n.String()}, nil
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(tok); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// formatBytes 以人类可读的单位格式化字节数
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlSample 覆盖需要加引号或转义的字符串
+type yamlSample struct {
+	Name    string                 `json:"name"`
+	Values  []string               `json:"values"`
+	Labels  map[string]string      `json:"labels"`
+	Size    int64                  `json:"size"`
+	Ratio   float64                `json:"ratio"`
+	Enabled bool                   `json:"enabled"`
+	Nothing interface{}            `json:"nothing"`
+	Empty   []string               `json:"empty"`
+	Nested  []map[string]yamlInner `json:"nested"`
+}
+
+type yamlInner struct {
+	Key   string   `json:"key"`
+	Items []string `json:"items"`
+}
+
+var yamlHostileStrings = []string{
+	"", " ", "plain", "yes", "no", "Yes", "NO", "on", "off", "y", "n", "true", "False",
+	"null", "Null", "~", "-", "- item", "-1", "0x1F", "0o17", "1e3", ".inf", "-.inf", ".nan", "1_000", "12:30",
+	":", ":x", "a: b", "#comment", "a #b", "a#b", "?", "? x", "!tag", "&anchor", "*alias", "|", ">",
+	"@x", "`x", "%x", "'single'", "\"double\"", "[a]", "{a: b}", "a, b",
+	"line1\nline2", "trailing\n", "\ttab", "trailing ", "back\\slash",
+	"中文模型", "émoji 🚀", " nbsp", "\x07bell", "\x00nul", "\u2028sep", "\ufeffbom",
+}
+
+// roundTrip 输出 YAML 后分别用 YAML 和 JSON 解析，结果应一致
+func roundTrip(t *testing.T, v interface{}) string {
+	t.Helper()
+	out, err := toYAML(v)
+	if err != nil {
+		t.Fatalf("toYAML: %v", err)
+	}
+	var got interface{}
+	if err := yaml.Unmarshal(out, &got); err != nil {
+		t.Fatalf("output is not valid YAML: %v\n%s", err, out)
+	}
+	data, _ := json.Marshal(v)
+	var want interface{}
+	json.Unmarshal(data, &want)
+	if !reflect.DeepEqual(normalizeYAML(got), want) {
+		t.Errorf("round trip mismatch\nyaml:\n%s\ngot:  %#v\nwant: %#v", out, normalizeYAML(got), want)
+	}
+	return string(out)
+}
+
+// normalizeYAML 把 YAML 解析结果转换为与 encoding/json 相同的类型
+func normalizeYAML(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		for k, item := range n {
+			n[k] = normalizeYAML(item)
+		}
+		return n
+	case []interface{}:
+		for i, item := range n {
+			n[i] = normalizeYAML(item)
+		}
+		return n
+	case int:
+		return float64(n)
+	}
+	return v
+}
+
+func TestToYAMLRoundTrip(t *testing.T) {
+	for _, s := range yamlHostileStrings {
+		roundTrip(t, map[string]string{"value": s})
+		roundTrip(t, map[string]string{s: "key"})
+		roundTrip(t, []string{s})
+	}
+
+	roundTrip(t, yamlSample{
+		Name:    "unsloth/Qwen3-8B-GGUF",
+		Values:  yamlHostileStrings,
+		Labels:  map[string]string{"yes": "no", "-": ":", "多行": "a\nb"},
+		Size:    1 << 40,
+		Ratio:   0.25,
+		Enabled: true,
+		Empty:   []string{},
+		Nested: []map[string]yamlInner{
+			{"a": {Key: "null", Items: []string{"~", "#"}}},
+			{},
+		},
+	})
+	roundTrip(t, []interface{}{})
+	roundTrip(t, map[string]interface{}{})
+	roundTrip(t, nil)
+}
+
+// YAML 1.1 的解析器会把 yes、on 等解析为布尔值，这些字符串必须加引号
+func TestToYAMLQuotesReservedWords(t *testing.T) {
+	for _, s := range []string{"yes", "no", "on", "off", "y", "n", "true", "null", "~", "-1", "1e3", "-", "#x"} {
+		out, err := toYAML(map[string]string{"v": s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plain := "v: " + s + "\n"; string(out) == plain {
+			t.Errorf("%q is not quoted: %s", s, out)
+		}
+	}
+}
+
+func TestToYAMLKeepsFieldOrder(t *testing.T) {
+	out := roundTrip(t, errorBody{Error: errorDetail{Code: errNotFound, ExitCode: 3, Message: "model \"x\" not found"}})
+	want := "error:\n  code: not_found\n  exit_code: 3\n  message: model \"x\" not found\n"
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+
+	out = roundTrip(t, struct {
+		Z string   `json:"z"`
+		A string   `json:"a"`
+		M []string `json:"m"`
+	}{Z: "1", A: "2", M: []string{"x"}})
+	if !strings.HasPrefix(out, "z: \"1\"\na: \"2\"\nm:\n  - x\n") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
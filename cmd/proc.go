@@ -0,0 +1,9 @@
+package cmd
+
+// procStats 进程组的资源占用，目前只在 Linux 上从 /proc 读取
+type procStats struct {
+	CPUSeconds  float64 // 用户态和内核态 CPU 时间之和
+	RSS         int64   // 常驻内存字节数
+	Threads     int     // 线程数
+	Connections int     // 已建立的 TCP 连接数
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// clockTicks /proc/<pid>/stat 中 CPU 时间的单位，Linux 上固定为 100
+const clockTicks = 100
+
+// tcpEstablished /proc/net/tcp 中 ESTABLISHED 状态的编码
+const tcpEstablished = "01"
+
+// readGroupStats 扫描一次 /proc，汇总 pgids 中每个进程组所有进程的资源占用，
+// llama-server 启动的子进程也计算在内。没有找到任何进程的进程组不在结果中。
+func readGroupStats(pgids map[int]bool) map[int]procStats {
+	stats := map[int]procStats{}
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return stats
+	}
+
+	sockets := map[int]map[string]bool{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// 进程可能在读取过程中退出，跳过即可
+		st, group, err := readProcStats(pid)
+		if err != nil || !pgids[group] {
+			continue
+		}
+		total := stats[group]
+		total.CPUSeconds += st.CPUSeconds
+		total.RSS += st.RSS
+		total.Threads += st.Threads
+		stats[group] = total
+		if sockets[group] == nil {
+			sockets[group] = map[string]bool{}
+		}
+		collectSocketInodes(pid, sockets[group])
+	}
+
+	if len(sockets) == 0 {
+		return stats
+	}
+	established := establishedSockets()
+	for group, inodes := range sockets {
+		total := stats[group]
+		for inode := range inodes {
+			if established[inode] {
+				total.Connections++
+			}
+		}
+		stats[group] = total
+	}
+	return stats
+}
+
+// readProcStats 读取 /proc/<pid>/stat 和 /proc/<pid>/statm，同时返回进程组 ID
+func readProcStats(pid int) (procStats, int, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return procStats{}, 0, err
+	}
+	// 进程名可能包含空格，从最后一个 ')' 之后开始解析
+	s := string(data)
+	i := strings.LastIndexByte(s, ')')
+	if i < 0 {
+		return procStats{}, 0, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(s[i+1:])
+	// fields[0] 是状态（第 3 个字段），pgrp、utime、stime、num_threads 分别是第 5、14、15、20 个字段
+	if len(fields) < 18 {
+		return procStats{}, 0, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	pgrp, _ := strconv.Atoi(fields[2])
+	utime, _ := strconv.ParseFloat(fields[11], 64)
+	stime, _ := strconv.ParseFloat(fields[12], 64)
+	threads, _ := strconv.Atoi(fields[17])
+
+	statm, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return procStats{}, 0, err
+	}
+	pages := strings.Fields(string(statm))
+	if len(pages) < 2 {
+		return procStats{}, 0, fmt.Errorf("malformed /proc/%d/statm", pid)
+	}
+	rss, _ := strconv.ParseInt(pages[1], 10, 64)
+
+	return procStats{
+		CPUSeconds: (utime + stime) / clockTicks,
+		RSS:        rss * int64(os.Getpagesize()),
+		Threads:    threads,
+	}, pgrp, nil
+}
+
+// collectSocketInodes 把进程打开的 socket 的 inode 加入 sockets
+func collectSocketInodes(pid int, sockets map[string]bool) {
+	dir := fmt.Sprintf("/proc/%d/fd", pid)
+	fds, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(dir, fd.Name()))
+		if err != nil {
+			continue
+		}
+		if inode, ok := strings.CutPrefix(target, "socket:["); ok {
+			sockets[strings.TrimSuffix(inode, "]")] = true
+		}
+	}
+}
+
+// establishedSockets 返回 /proc/net/tcp 和 /proc/net/tcp6 中已建立连接的 socket inode
+func establishedSockets() map[string]bool {
+	established := map[string]bool{}
+	for _, name := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Scan() // 跳过表头
+		for scanner.Scan() {
+			// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 10 && fields[3] == tcpEstablished {
+				established[fields[9]] = true
+			}
+		}
+		f.Close()
+	}
+	return established
+}
+
+// Linux 上 waitid 的参数，syscall 包中没有定义
+const (
+	waitidPID  = 1         // P_PID
+	waitNoWait = 0x1000000 // WNOWAIT
+)
+
+// waitExited 阻塞到进程 pid 退出，但不回收它，之后仍需调用 cmd.Wait。
+// 进程被回收之前其 ID 不会被系统复用。
+func waitExited(pid int) error {
+	var siginfo [16]uint64
+	for {
+		_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, waitidPID, uintptr(pid), uintptr(unsafe.Pointer(&siginfo)), syscall.WEXITED|waitNoWait, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return errno
+		}
+		return nil
+	}
+}
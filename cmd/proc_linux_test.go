@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReadGroupStats(t *testing.T) {
+	cmd := startGroup(t, "sleep", "30")
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	pgid := cmd.Process.Pid
+
+	stats := readGroupStats(map[int]bool{pgid: true, 1 << 30: true})
+	st, ok := stats[pgid]
+	if !ok {
+		t.Fatalf("process group %d not found in %v", pgid, stats)
+	}
+	if st.Threads < 1 || st.RSS <= 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+	if _, ok := stats[1<<30]; ok {
+		t.Error("stats reported for a process group that does not exist")
+	}
+}
+
+func TestSampleInstanceStats(t *testing.T) {
+	cmd := startGroup(t, "sleep", "30")
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	mp := &ModelProcess{ID: -1, Status: statusRunning, Command: cmd, started: time.Now(), sampledAt: time.Now()}
+	exited := &ModelProcess{ID: -2, Status: statusRunning, Command: cmd, exited: true}
+	modelMux.Lock()
+	models[mp.ID], models[exited.ID] = mp, exited
+	modelMux.Unlock()
+	defer func() {
+		modelMux.Lock()
+		delete(models, mp.ID)
+		delete(models, exited.ID)
+		modelMux.Unlock()
+	}()
+
+	modelMux.Lock()
+	if s := mp.status(); s.RSSBytes != 0 || s.Threads != 0 {
+		t.Errorf("stats reported before the first sample: %+v", s)
+	}
+	modelMux.Unlock()
+
+	sampleInstanceStats()
+
+	modelMux.Lock()
+	defer modelMux.Unlock()
+	if s := mp.status(); s.RSSBytes <= 0 || s.Threads < 1 {
+		t.Errorf("no stats after sampling: %+v", s)
+	}
+	if exited.stats != nil {
+		t.Error("an exited process was sampled")
+	}
+}
+
+// waitExited 返回后进程尚未被回收，其进程 ID 仍然有效
+func TestWaitExitedDoesNotReap(t *testing.T) {
+	cmd := startGroup(t, "true")
+	if err := waitExited(cmd.Process.Pid); err != nil {
+		t.Fatalf("waitExited: %v", err)
+	}
+	if err := syscall.Kill(cmd.Process.Pid, 0); err != nil {
+		t.Errorf("process was reaped by waitExited: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("Wait: %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// readGroupStats 其他系统上没有 /proc，不报告资源占用
+func readGroupStats(pgids map[int]bool) map[int]procStats {
+	return map[int]procStats{}
+}
+
+// waitExited 其他系统上不能在不回收进程的情况下等待它退出，
+// 调用方应改为在 cmd.Wait 返回后再标记进程已退出
+func waitExited(pid int) error {
+	return fmt.Errorf("waiting for a process without reaping it is not supported on %s", runtime.GOOS)
+}
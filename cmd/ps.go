@@ -1,21 +1,46 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"oneinfer/client"
 
 	"github.com/spf13/cobra"
 )
 
+// instanceListOutput ps 命令的输出结构
+type instanceListOutput struct {
+	Instances []client.Instance `json:"instances"`
+}
+
 // ps 命令
 var psCmd = &cobra.Command{
 	Use:   "ps",
 	Short: "List all running models",
-	Run: func(cmd *cobra.Command, args []string) {
-		listRunningModels()
+	Long: `List all running models with their status and resource usage.
+
+CPU, memory, threads and connections are summed over each instance's process group.
+CPU% is relative to one core, so a model using four cores shows 400%.`,
+	Args: validArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		models, err := listRunningModels()
+		if err != nil {
+			return err
+		}
+
+		return printResult(instanceListOutput{Instances: models}, func(w io.Writer) {
+			// 没有运行的模型
+			if len(models) == 0 {
+				io.WriteString(w, "No running models found.\n")
+				return
+			}
+			writeInstanceTable(w, models)
+		})
 	},
 }
 
@@ -23,47 +48,95 @@ func init() {
 	rootCmd.AddCommand(psCmd)
 }
 
-// 获取所有运行的模型
-func listRunningModels() {
-	resp, err := http.Get("http://127.0.0.1:9090/models")
-	if err != nil {
-		fmt.Println("Error: Unable to connect to oneinfer service.")
-		os.Exit(1)
+// writeInstanceTable 以表格输出实例列表，ps 和 top 共用
+func writeInstanceTable(w io.Writer, models []client.Instance) {
+	t := &table{
+		headers:     []string{"ID", "MODEL", "HOST", "PORT", "STATUS", "UPTIME", "CPU%", "MEM", "THREADS", "CONNS", "RESTARTS"},
+		wideHeaders: []string{"URL"},
+	}
+	for _, model := range models {
+		name := model.Name
+		if name == "" {
+			name = model.Model
+		}
+		// 没有进程的实例不显示资源占用
+		uptime, cpu, mem, threads, conns := "-", "-", "-", "-", "-"
+		if model.Status == statusStarting || model.Status == statusRunning {
+			uptime = formatUptime(model.UptimeSeconds)
+			cpu = strconv.FormatFloat(model.CPUPercent, 'f', 1, 64)
+			mem = formatBytes(model.RSSBytes)
+			threads = strconv.Itoa(model.Threads)
+			conns = strconv.Itoa(model.Connections)
+		}
+		t.addRow(
+			[]string{strconv.Itoa(model.ID), name, model.Host, strconv.Itoa(model.Port), model.Status,
+				uptime, cpu, mem, threads, conns, strconv.Itoa(model.Restarts)},
+			fmt.Sprintf("http://%s:%d", model.Host, model.Port),
+		)
 	}
-	defer resp.Body.Close()
+	t.write(w)
+}
 
-	// 处理 HTTP 错误码
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Error: Server returned status %d\n", resp.StatusCode)
-		os.Exit(1)
+// formatUptime 把秒数格式化为 45s、12m30s、3h05m、2d04h 的形式
+func formatUptime(seconds int64) string {
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", seconds)
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", seconds/60, seconds%60)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%02dm", seconds/3600, seconds%3600/60)
 	}
+	return fmt.Sprintf("%dd%02dh", seconds/86400, seconds%86400/3600)
+}
 
-	// 读取并打印响应体
-	body, err := ioutil.ReadAll(resp.Body)
+// resolveInstanceID 把实例 ID、模型名或别名解析为运行中实例的 ID
+func resolveInstanceID(arg string) (int, error) {
+	if id, err := strconv.Atoi(arg); err == nil {
+		return id, nil
+	}
+	model, err := findModel(arg)
 	if err != nil {
-		fmt.Println("Error: Unable to read the response body.")
-		os.Exit(1)
+		if kindOf(err) == errNotFound {
+			return 0, newError(errNotFound, "%q is neither an instance ID nor a model name or alias", arg)
+		}
+		return 0, err
 	}
-
-	// 解析 JSON 响应
-	var models []ModelProcessStatus
-	if err := json.Unmarshal(body, &models); err != nil {
-		fmt.Println("Error: Failed to parse response:", err)
-		os.Exit(1)
+	instances, err := listRunningModels()
+	if err != nil {
+		return 0, err
 	}
+	return instanceOfModel(instances, model.Name)
+}
 
-	// 没有运行的模型
-	if len(models) == 0 {
-		fmt.Println("No running models found.")
-		return
+// instanceOfModel 返回模型唯一的运行中实例，有多个实例时要求指定 ID
+func instanceOfModel(instances []client.Instance, name string) (int, error) {
+	var ids []string
+	id := 0
+	for _, instance := range instances {
+		if instance.Name == name {
+			id = instance.ID
+			ids = append(ids, strconv.Itoa(instance.ID))
+		}
 	}
+	switch len(ids) {
+	case 0:
+		return 0, newError(errNotFound, "model '%s' has no running instance", name)
+	case 1:
+		return id, nil
+	}
+	return 0, newError(errValidation, "model '%s' has %d running instances (%s), specify an instance ID", name, len(ids), strings.Join(ids, ", "))
+}
 
-	// 输出表头
-	fmt.Printf("%-10s %-20s %-15s %-6s %-10s\n", "PID", "Model", "Host", "Port", "Status")
-	fmt.Println("--------------------------------------------------------------")
-
-	// 输出每个模型的信息
-	for _, model := range models {
-		fmt.Printf("%-10d %-20s %-15s %-6d %-10s\n", model.ID, model.Model, model.Host, model.Port, model.Status)
+// 获取所有运行的模型，serve 已按 ID 排序
+func listRunningModels() ([]client.Instance, error) {
+	models, err := apiClient().ListInstances(context.Background())
+	if err != nil {
+		return nil, clientError(err)
+	}
+	if models == nil {
+		models = []client.Instance{}
 	}
+	return models, nil
 }
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"testing"
+
+	"oneinfer/client"
+)
+
+// stop 和 logs 按模型名解析实例时，只有唯一的运行中实例才能被选中
+func TestInstanceOfModel(t *testing.T) {
+	instances := []client.Instance{
+		{ID: 1, Name: "qwen"},
+		{ID: 2, Name: "llama"},
+		{ID: 3, Name: "llama"},
+		{ID: 4},
+	}
+
+	if id, err := instanceOfModel(instances, "qwen"); err != nil || id != 1 {
+		t.Errorf("qwen: got %d, %v, want 1", id, err)
+	}
+	if _, err := instanceOfModel(instances, "llama"); kindOf(err) != errValidation {
+		t.Errorf("llama: got %v, want a validation error for two instances", err)
+	}
+	if _, err := instanceOfModel(instances, "mistral"); kindOf(err) != errNotFound {
+		t.Errorf("mistral: got %v, want not found", err)
+	}
+}
+
+// 数字参数直接作为实例 ID，不查询注册表和 serve
+func TestResolveInstanceIDNumeric(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if id, err := resolveInstanceID("7"); err != nil || id != 7 {
+		t.Errorf("got %d, %v, want 7", id, err)
+	}
+	if _, err := resolveInstanceID("missing"); kindOf(err) != errNotFound {
+		t.Errorf("unknown name: got %v, want not found", err)
+	}
+}
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// 远程模型的更新状态
+const (
+	revisionUpToDate = "up-to-date"
+	revisionOutdated = "outdated"
+	revisionPinned   = "pinned"
+	revisionUnknown  = "unknown"
+)
+
+// revisionStatus 远程模型与平台上最新提交的比较结果
+type revisionStatus struct {
+	Name     string `json:"name"`
+	Platform string `json:"platform"`
+	Source   string `json:"source"`
+	Revision string `json:"revision"`
+	Current  string `json:"current"`
+	Latest   string `json:"latest,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// outdatedOutput outdated 命令的输出结构
+type outdatedOutput struct {
+	Models []revisionStatus `json:"models"`
+}
+
+// pullOutput pull 命令的输出结构
+type pullOutput struct {
+	Name      string `json:"name"`
+	Revision  string `json:"revision"`
+	OldCommit string `json:"old_commit"`
+	NewCommit string `json:"new_commit"`
+	Updated   bool   `json:"updated"`
+}
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "Check the hub for newer commits of models added from Hugging Face or ModelScope",
+	Args:  validArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		models, err := listModels()
+		if err != nil {
+			return err
+		}
+
+		out := outdatedOutput{Models: []revisionStatus{}}
+		for _, model := range models {
+			if !isSupportedPlatform(model.Platform) {
+				continue
+			}
+			out.Models = append(out.Models, checkRevision(model))
+		}
+
+		return printResult(out, func(w io.Writer) {
+			if len(out.Models) == 0 {
+				fmt.Fprintln(w, "No models from Hugging Face or ModelScope found.")
+				return
+			}
+			t := &table{
+				headers:     []string{"MODEL", "REVISION", "CURRENT", "LATEST", "STATUS"},
+				wideHeaders: []string{"ERROR"},
+			}
+			for _, s := range out.Models {
+				t.addRow([]string{s.Name, s.Revision, shortCommit(s.Current), shortCommit(s.Latest), s.Status}, s.Error)
+			}
+			t.write(w)
+		})
+	},
+}
+
+var pullCmd = &cobra.Command{
+	Use:               "pull <model_name>",
+	Short:             "Update a Hugging Face or ModelScope model to the latest commit of its revision",
+	Long:              "Download the latest commit of the model's recorded revision. The old files are kept until the new ones have been downloaded and verified, then the model is switched over atomically.",
+	Args:              validArgs(cobra.ExactArgs(1)),
+	ValidArgsFunction: completeModelNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		installDeps, _ := cmd.Flags().GetBool("install-deps")
+
+		model, err := findModel(args[0])
+		if err != nil {
+			return err
+		}
+		out, err := pullModel(model, force, installDeps)
+		if err != nil {
+			return err
+		}
+
+		return printResult(out, func(w io.Writer) {
+			if !out.Updated {
+				fmt.Fprintf(w, "Model '%s' is up to date (%s@%s).\n", out.Name, out.Revision, shortCommit(out.NewCommit))
+				return
+			}
+			fmt.Fprintf(w, "Model '%s' updated: %s -> %s\n", out.Name, shortCommit(out.OldCommit), shortCommit(out.NewCommit))
+		})
+	},
+}
+
+func init() {
+	pullCmd.Flags().Bool("force", false, "Download again even if the model is already at the latest commit")
+	pullCmd.Flags().Bool("install-deps", false, "Install missing Python download libraries with pip")
+	rootCmd.AddCommand(outdatedCmd)
+	rootCmd.AddCommand(pullCmd)
+}
+
+// shortCommit 返回提交 SHA 的前 12 位
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// checkRevision 查询平台上 revision 的最新提交并与记录的提交比较
+func checkRevision(model modelInfo) revisionStatus {
+	s := revisionStatus{
+		Name:     model.Name,
+		Platform: model.Platform,
+		Source:   model.Source,
+		Revision: model.Revision,
+		Current:  model.Commit,
+	}
+	// 旧版本注册的模型没有记录来源
+	if s.Source == "" {
+		s.Status, s.Error = revisionUnknown, "no source recorded (added by an older version of oneinfer)"
+		return s
+	}
+	if s.Revision == "" {
+		s.Revision = defaultRevisions[model.Platform]
+	}
+	if isPinned(s.Revision) {
+		s.Status, s.Latest = revisionPinned, s.Revision
+		return s
+	}
+
+	latest, err := resolveRevision(model.Platform, model.Source, s.Revision)
+	if err != nil {
+		s.Status, s.Error = revisionUnknown, err.Error()
+		return s
+	}
+	s.Latest = latest
+	if latest == model.Commit {
+		s.Status = revisionUpToDate
+	} else {
+		s.Status = revisionOutdated
+	}
+	return s
+}
+
+// pullModel 下载模型 revision 的最新提交，校验通过后原子地切换视图目录和注册表记录
+func pullModel(model modelInfo, force, installDeps bool) (pullOutput, error) {
+	if !isSupportedPlatform(model.Platform) {
+		return pullOutput{}, newError(errValidation, "model '%s' was not added from Hugging Face or ModelScope", model.Name)
+	}
+	status := checkRevision(model)
+	out := pullOutput{Name: model.Name, Revision: status.Revision, OldCommit: model.Commit, NewCommit: status.Latest}
+	switch status.Status {
+	case revisionUnknown:
+		return out, fmt.Errorf("cannot check for updates: %s", status.Error)
+	case revisionUpToDate, revisionPinned:
+		if !force {
+			out.NewCommit = model.Commit
+			return out, nil
+		}
+	}
+
+	// 新文件先存入 blob 仓库，旧文件保持不变
+	fmt.Fprintf(infoOut(), "Downloading %s@%s...\n", model.Source, shortCommit(status.Latest))
+	revision := downloadRevision(model.Platform, status.Revision, status.Latest)
+	lease, err := newBlobLease()
+	if err != nil {
+		return out, err
+	}
+	defer lease.release()
+	manifest, err := downloadHubFiles(context.Background(), model.Platform, model.Source, revision, model.FilePattern, addOptions{InstallDeps: installDeps}, lease)
+	if err != nil {
+		return out, err
+	}
+
+	// 按 hub 公布的摘要校验新文件，失败时删除新下载且未被引用的 blob，保留旧版本
+	hubFiles, err := listHubFiles(model.Platform, model.Source, revision)
+	if err != nil {
+		lease.release()
+		pruneBlobs(manifest)
+		return out, fmt.Errorf("cannot fetch checksums of %s@%s, keeping the current version: %v", model.Source, shortCommit(status.Latest), err)
+	}
+	updated := model
+	updated.Files = manifest
+	if check := verifyModelFiles(updated, hubFiles); check != "" {
+		lease.release()
+		pruneBlobs(manifest)
+		return out, fmt.Errorf("downloaded files failed verification, keeping the current version: %s", check)
+	}
+
+	// 在旁边创建新的视图目录，再通过重命名切换
+	view, err := modelViewDir(model.Name)
+	if err != nil {
+		return out, err
+	}
+	next, old := view+".new", view+".old"
+	os.RemoveAll(next)
+	os.RemoveAll(old)
+	if err := linkModelFiles(next, manifest); err != nil {
+		return out, err
+	}
+	if err := os.Rename(view, old); err != nil && !os.IsNotExist(err) {
+		os.RemoveAll(next)
+		return out, err
+	}
+	if err := os.Rename(next, view); err != nil {
+		os.Rename(old, view)
+		os.RemoveAll(next)
+		return out, err
+	}
+
+	oldFiles := model.Files
+	if _, err := updateModel(model.Name, func(models []modelInfo, m *modelInfo) error {
+		m.setFiles(view, manifest)
+		m.Revision = status.Revision
+		m.Commit = status.Latest
+		return nil
+	}); err != nil {
+		// 注册表更新失败时恢复旧的视图目录
+		os.RemoveAll(view)
+		os.Rename(old, view)
+		return out, err
+	}
+	os.RemoveAll(old)
+	lease.release()
+
+	// 删除旧版本中不再被引用的 blob
+	if err := pruneBlobs(oldFiles); err != nil {
+		return out, err
+	}
+
+	out.Updated = true
+	return out, nil
+}
+
+// verifyModelFiles 重新计算清单中所有文件的摘要，与 hub 公布的摘要（LFS sha256）和大小比较，并检查 GGUF 结构。
+// 返回第一个问题的描述，全部通过时返回空字符串。
+func verifyModelFiles(model modelInfo, hubFiles []hubFile) string {
+	published := map[string]hubFile{}
+	for _, f := range hubFiles {
+		published[f.Path] = f
+	}
+	for _, f := range model.Files {
+		if hf, ok := published[f.Path]; ok {
+			if hf.Digest != "" && hf.Digest != f.Digest {
+				return fmt.Sprintf("%s: %s expected %s, downloaded %s", f.Path, checkHashMismatch, hf.Digest, f.Digest)
+			}
+			if hf.Size != f.Size {
+				return fmt.Sprintf("%s: %s expected %d bytes, downloaded %d", f.Path, checkSizeMismatch, hf.Size, f.Size)
+			}
+		}
+		fc := verifyFile(f, true)
+		if fc.Status == checkOK {
+			fc.Status, fc.Detail, _ = checkGGUFStructure(fc.Location, nil)
+		}
+		if fc.Status != checkOK {
+			return fmt.Sprintf("%s: %s %s", f.Path, fc.Status, fc.Detail)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyModelFilesAgainstHub(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	src := filepath.Join(home, "m.gguf")
+	os.WriteFile(src, validGGUF(), 0644)
+	file, err := storeBlob(src, importCopy, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Path = "m.gguf"
+	model := modelInfo{Name: "m", Files: []modelFile{file}}
+
+	tests := []struct {
+		name string
+		hub  []hubFile
+		want string
+	}{
+		{"matching digest", []hubFile{{Path: "m.gguf", Size: file.Size, Digest: file.Digest}}, ""},
+		{"no digest published", []hubFile{{Path: "m.gguf", Size: file.Size}}, ""},
+		{"other digest", []hubFile{{Path: "m.gguf", Size: file.Size, Digest: "sha256:" + strings.Repeat("ab", 32)}}, checkHashMismatch},
+		{"other size", []hubFile{{Path: "m.gguf", Size: file.Size + 1}}, checkSizeMismatch},
+	}
+	for _, tt := range tests {
+		got := verifyModelFiles(model, tt.hub)
+		if tt.want == "" && got != "" || tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("%s: verifyModelFiles = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// 存入仓库后被改动的 blob 同样不能通过
+	blob, _ := file.filePath()
+	data := validGGUF()
+	data[len(data)-1] ^= 0xff
+	os.WriteFile(blob, data, 0644)
+	if got := verifyModelFiles(model, nil); !strings.Contains(got, checkHashMismatch) {
+		t.Errorf("changed blob: verifyModelFiles = %q", got)
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fitMemoryOverhead 按模型文件大小估算运行时内存时预留的比例（KV 缓存、计算缓冲区等）
+const fitMemoryOverhead = 1.2
+
+// ggufSet 仓库中的一个 GGUF 模型，分片模型的所有分片合并为一个
+type ggufSet struct {
+	Name    string   // 单文件为文件路径，分片模型为去掉分片后缀的路径
+	Pattern string   // 下载使用的文件模式
+	Quant   string   // 量化类型
+	Files   []string // 包含的文件
+	Size    int64    // 总字节数
+}
+
+// shardSetPattern 把分片文件名扩展为匹配整组分片的文件模式，非分片文件返回空
+func shardSetPattern(file string) string {
+	m := shardPattern.FindStringSubmatchIndex(file)
+	if m == nil {
+		return ""
+	}
+	return file[:m[0]] + "-*-of-" + file[m[4]:m[5]] + ".gguf"
+}
+
+// expandShardPattern 用户指定某一个分片时扩展为整组分片，其他模式原样返回
+func expandShardPattern(pattern string) string {
+	if strings.ContainsAny(pattern, "*?[") {
+		return pattern
+	}
+	if p := shardSetPattern(pattern); p != "" {
+		return p
+	}
+	return pattern
+}
+
+// groupGGUFSets 把仓库文件按模型分组，跳过 mmproj 和非 GGUF 文件，按名称排序
+func groupGGUFSets(files []hubFile) []ggufSet {
+	sets := map[string]*ggufSet{}
+	var names []string
+	for _, f := range files {
+		base := path.Base(f.Path)
+		if !strings.HasSuffix(strings.ToLower(base), ".gguf") || strings.Contains(strings.ToLower(base), "mmproj") {
+			continue
+		}
+		name, pattern := f.Path, f.Path
+		if p := shardSetPattern(f.Path); p != "" {
+			name = shardPattern.ReplaceAllString(f.Path, "")
+			pattern = p
+		}
+		set, ok := sets[pattern]
+		if !ok {
+			set = &ggufSet{Name: name, Pattern: pattern, Quant: quantLabel(f.Path)}
+			sets[pattern] = set
+			names = append(names, pattern)
+		}
+		set.Files = append(set.Files, f.Path)
+		set.Size += f.Size
+	}
+
+	sort.Strings(names)
+	result := make([]ggufSet, 0, len(names))
+	for _, n := range names {
+		result = append(result, *sets[n])
+	}
+	return result
+}
+
+// selectGGUFSet 按量化类型和可用内存从仓库文件中选出要下载的模型。
+// quant 为空时不按量化类型过滤；fitMemory 为 true 时选择能放入可用内存的最大模型，选择结果输出到 log。
+func selectGGUFSet(files []hubFile, quant string, fitMemory bool, log *slog.Logger) (ggufSet, error) {
+	sets := groupGGUFSets(files)
+	if len(sets) == 0 {
+		return ggufSet{}, newError(errNotFound, "no GGUF files found in the repository")
+	}
+
+	if quant != "" {
+		var matched []ggufSet
+		var available []string
+		seen := map[string]bool{}
+		for _, s := range sets {
+			if strings.EqualFold(s.Quant, quant) {
+				matched = append(matched, s)
+			}
+			if s.Quant != "" && !seen[s.Quant] {
+				seen[s.Quant] = true
+				available = append(available, s.Quant)
+			}
+		}
+		if len(matched) == 0 {
+			return ggufSet{}, newError(errNotFound, "no %s file in the repository, available quantizations: %s", strings.ToUpper(quant), strings.Join(available, ", "))
+		}
+		sets = matched
+	}
+
+	if !fitMemory {
+		if len(sets) > 1 {
+			names := make([]string, 0, len(sets))
+			for _, s := range sets {
+				names = append(names, s.Pattern)
+			}
+			return ggufSet{}, newError(errValidation, "%d files match, specify one as the file pattern: %s", len(sets), strings.Join(names, ", "))
+		}
+		return sets[0], nil
+	}
+
+	available, err := availableMemory()
+	if err != nil {
+		return ggufSet{}, fmt.Errorf("cannot determine available memory: %v", err)
+	}
+	var best *ggufSet
+	for i, s := range sets {
+		if float64(s.Size)*fitMemoryOverhead > float64(available) {
+			continue
+		}
+		if best == nil || s.Size > best.Size {
+			best = &sets[i]
+		}
+	}
+	if best == nil {
+		return ggufSet{}, newError(errNotFound, "no model in the repository fits in the %s of available memory", formatBytes(available))
+	}
+	infof(log, "Selected %s (%s, %s available)", best.Name, formatBytes(best.Size), formatBytes(available))
+	return *best, nil
+}
+
+// availableMemory 从 /proc/meminfo 读取主机当前可用内存的字节数
+func availableMemory() (int64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return kb * 1024, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("MemAvailable not found in /proc/meminfo")
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShardSetPattern(t *testing.T) {
+	tests := []struct{ file, want string }{
+		{"Qwen3-235B-Q4_K_M-00001-of-00003.gguf", "Qwen3-235B-Q4_K_M-*-of-00003.gguf"},
+		{"Q4_K_M/model-00002-of-00005.gguf", "Q4_K_M/model-*-of-00005.gguf"},
+		{"model-Q4_K_M.gguf", ""},
+		{"model-00001-of-00003.bin", ""},
+		{"model-1-of-3.gguf", ""},
+	}
+	for _, tt := range tests {
+		if got := shardSetPattern(tt.file); got != tt.want {
+			t.Errorf("shardSetPattern(%q) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestExpandShardPattern(t *testing.T) {
+	tests := []struct{ pattern, want string }{
+		{"model-00002-of-00003.gguf", "model-*-of-00003.gguf"},
+		{"*-00001-of-00003.gguf", "*-00001-of-00003.gguf"},
+		{"model-Q4_K_M.gguf", "model-Q4_K_M.gguf"},
+		{"*.gguf", "*.gguf"},
+	}
+	for _, tt := range tests {
+		if got := expandShardPattern(tt.pattern); got != tt.want {
+			t.Errorf("expandShardPattern(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+// mixedRepo 同时包含单文件、分片、mmproj 和其他文件的仓库
+var mixedRepo = []hubFile{
+	{Path: "README.md", Size: 1},
+	{Path: "Qwen3-Q8_0-00002-of-00002.gguf", Size: 50},
+	{Path: "Qwen3-Q4_K_M.gguf", Size: 40},
+	{Path: "mmproj-Qwen3-F16.gguf", Size: 5},
+	{Path: "Qwen3-Q8_0-00001-of-00002.gguf", Size: 60},
+	{Path: "BF16/Qwen3-BF16-00001-of-00003.gguf", Size: 100},
+	{Path: "BF16/Qwen3-BF16-00002-of-00003.gguf", Size: 100},
+	{Path: "BF16/Qwen3-BF16-00003-of-00003.gguf", Size: 20},
+	{Path: "Qwen3-Q2_K.GGUF", Size: 20},
+}
+
+func TestGroupGGUFSets(t *testing.T) {
+	got := groupGGUFSets(mixedRepo)
+	want := []ggufSet{
+		{Name: "BF16/Qwen3-BF16", Pattern: "BF16/Qwen3-BF16-*-of-00003.gguf", Quant: "BF16", Size: 220, Files: []string{
+			"BF16/Qwen3-BF16-00001-of-00003.gguf", "BF16/Qwen3-BF16-00002-of-00003.gguf", "BF16/Qwen3-BF16-00003-of-00003.gguf",
+		}},
+		{Name: "Qwen3-Q2_K.GGUF", Pattern: "Qwen3-Q2_K.GGUF", Quant: "Q2_K", Size: 20, Files: []string{"Qwen3-Q2_K.GGUF"}},
+		{Name: "Qwen3-Q4_K_M.gguf", Pattern: "Qwen3-Q4_K_M.gguf", Quant: "Q4_K_M", Size: 40, Files: []string{"Qwen3-Q4_K_M.gguf"}},
+		{Name: "Qwen3-Q8_0", Pattern: "Qwen3-Q8_0-*-of-00002.gguf", Quant: "Q8_0", Size: 110, Files: []string{
+			"Qwen3-Q8_0-00002-of-00002.gguf", "Qwen3-Q8_0-00001-of-00002.gguf",
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groupGGUFSets:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+// 同名但分片数不同的两组分片不能合并
+func TestGroupGGUFSetsDistinctShardCounts(t *testing.T) {
+	got := groupGGUFSets([]hubFile{
+		{Path: "m-Q4_K_M-00001-of-00002.gguf", Size: 1},
+		{Path: "m-Q4_K_M-00002-of-00002.gguf", Size: 1},
+		{Path: "m-Q4_K_M-00001-of-00003.gguf", Size: 1},
+	})
+	if len(got) != 2 || len(got[0].Files) != 2 || len(got[1].Files) != 1 {
+		t.Errorf("unexpected sets: %+v", got)
+	}
+}
+
+func TestSelectGGUFSet(t *testing.T) {
+	set, err := selectGGUFSet(mixedRepo, "q8_0", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Pattern != "Qwen3-Q8_0-*-of-00002.gguf" || len(set.Files) != 2 {
+		t.Errorf("unexpected set: %+v", set)
+	}
+
+	_, err = selectGGUFSet(mixedRepo, "Q5_K_M", false, nil)
+	if kindOf(err) != errNotFound || !strings.Contains(err.Error(), "BF16, Q2_K, Q4_K_M, Q8_0") {
+		t.Errorf("missing quant: error = %v", err)
+	}
+
+	_, err = selectGGUFSet(mixedRepo, "", false, nil)
+	if kindOf(err) != errValidation || !strings.Contains(err.Error(), "4 files match") {
+		t.Errorf("ambiguous selection: error = %v", err)
+	}
+
+	_, err = selectGGUFSet([]hubFile{{Path: "README.md"}, {Path: "mmproj-F16.gguf"}}, "", false, nil)
+	if kindOf(err) != errNotFound {
+		t.Errorf("no GGUF files: error = %v", err)
+	}
+}
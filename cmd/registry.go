@@ -0,0 +1,730 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 目录布局：
+//
+//	~/.oneinfer/models/models.json   注册表，每条记录带有自己的文件清单（manifest）
+//	~/.oneinfer/models/models.json.lock  修改注册表时持有的文件锁
+//	~/.oneinfer/blobs/sha256-<hex>    按内容寻址的模型文件，相同内容只保存一份
+//	~/.oneinfer/models/<name>/...     每个模型的视图目录，按原始文件名链接到 blob，供 llama-server 加载
+//	~/.oneinfer/tmp/                  下载暂存目录
+//	~/.oneinfer/leases/lease-*        进行中的导入持有的 blob 租约
+
+// blobMove 把下载暂存目录中的文件移动到 blob 仓库
+const blobMove = "move"
+
+// modelFile 模型清单中的一个文件
+type modelFile struct {
+	Path     string `json:"path"`               // 相对于模型视图目录的路径
+	Digest   string `json:"digest"`             // sha256:<hex>
+	Size     int64  `json:"size"`               // 字节数
+	External string `json:"external,omitempty"` // symlink/inplace 导入时文件的原始位置，不在 blob 仓库中
+}
+
+// oneinferDir 返回 ~/.oneinfer
+func oneinferDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".oneinfer"), nil
+}
+
+// modelsDir 返回模型视图目录的根目录 ~/.oneinfer/models
+func modelsDir() (string, error) {
+	dir, err := oneinferDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "models"), nil
+}
+
+// blobsDir 返回 blob 仓库目录 ~/.oneinfer/blobs
+func blobsDir() (string, error) {
+	dir, err := oneinferDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "blobs"), nil
+}
+
+// leasesDir 返回 blob 租约目录 ~/.oneinfer/leases
+func leasesDir() (string, error) {
+	dir, err := oneinferDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "leases"), nil
+}
+
+// registryPath 返回注册表文件路径
+func registryPath() (string, error) {
+	dir, err := modelsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "models.json"), nil
+}
+
+// viewDirName 把模型名转换为视图目录名，"/" 替换为 "--"。
+// 为了让不同的模型名对应不同的目录，"%" 以及与 "-" 或 "/" 相邻的 "-" 按 URL 编码转义为 "%25"、"%2D"，
+// 这样结果中的 "--" 只可能来自 "/"，如 a/b--c 对应 a--b%2D%2Dc，a--b/c 对应 a%2D%2Db--c。
+// 普通的模型名（如 unsloth/Qwen3-8B-GGUF）转换结果不变。
+func viewDirName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case c == '/':
+			b.WriteString("--")
+		case c == '%':
+			b.WriteString("%25")
+		case c == '-' && (i > 0 && strings.ContainsRune("-/", rune(name[i-1])) ||
+			i+1 < len(name) && strings.ContainsRune("-/", rune(name[i+1]))):
+			b.WriteString("%2D")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// blobPath 返回摘要对应的 blob 路径
+func blobPath(digest string) (string, error) {
+	hexDigest, ok := strings.CutPrefix(digest, "sha256:")
+	if !ok || len(hexDigest) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	dir, err := blobsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sha256-"+hexDigest), nil
+}
+
+// filePath 返回清单中文件的实际位置
+func (f modelFile) filePath() (string, error) {
+	if f.External != "" {
+		return f.External, nil
+	}
+	return blobPath(f.Digest)
+}
+
+// loadRegistry 读取注册表，文件不存在时返回空列表
+func loadRegistry() ([]modelInfo, error) {
+	metaPath, err := registryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	models := []modelInfo{}
+	file, err := os.ReadFile(metaPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return models, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(file, &models); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", metaPath, err)
+	}
+	if models == nil {
+		models = []modelInfo{}
+	}
+	return models, nil
+}
+
+// lockRegistry 对 models.json.lock 加排他的文件锁，返回解锁函数。
+// 注册表的读-改-写都要持有该锁：serve 的多个下载任务、API 请求和 CLI 进程可能同时修改注册表，
+// 否则后写入的一方会覆盖前者的修改。flock 按打开的文件生效，同一进程中的多个 goroutine 之间同样互斥。
+func lockRegistry() (func(), error) {
+	metaPath, err := registryPath()
+	if err != nil {
+		return nil, err
+	}
+	return lockFile(metaPath + ".lock")
+}
+
+// lockFile 对 path 加排他的文件锁，返回解锁函数，锁文件不存在时创建
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %v", f.Name(), err)
+	}
+	return func() { f.Close() }, nil
+}
+
+// blobLease 一次导入的租约，记录已存入 blob 仓库、还没有被注册表引用的 blob。
+// 租约文件在导入期间一直持有文件锁，删除模型和 gc 不会删除其中的 blob；
+// 进程异常退出后锁随之释放，残留的租约文件在下次读取时被删除。
+type blobLease struct {
+	f    *os.File
+	path string
+}
+
+// newBlobLease 创建租约文件。文件先以 .tmp 后缀创建并加锁，再重命名为 lease-*，
+// 读取方因此不会把还没来得及加锁的租约当作残留删除。
+func newBlobLease() (*blobLease, error) {
+	dir, err := leasesDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, "lease-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimSuffix(f.Name(), ".tmp")
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("failed to create blob lease: %v", err)
+	}
+	return &blobLease{f: f, path: path}, nil
+}
+
+// hold 把摘要加入租约。lease 为 nil 时不记录，用于恢复已注册模型的文件。
+func (l *blobLease) hold(digest string) error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	_, err := fmt.Fprintln(l.f, digest)
+	return err
+}
+
+// release 在模型注册之后或导入失败时释放租约，可以重复调用
+func (l *blobLease) release() {
+	if l == nil || l.f == nil {
+		return
+	}
+	os.Remove(l.path)
+	l.f.Close()
+	l.f = nil
+}
+
+// leasedBlobs 返回进行中的导入持有的 blob 摘要，并删除导入进程退出后残留的租约文件。
+// 调用方需持有 lockRegistry 的锁。
+func leasedBlobs() (map[string]bool, error) {
+	leased := map[string]bool{}
+	dir, err := leasesDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return leased, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "lease-") || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		f, err := os.Open(path)
+		if err != nil {
+			// 租约刚被释放
+			continue
+		}
+		if syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB) == nil {
+			// 没有进程持有锁，导入进程已经退出
+			f.Close()
+			os.Remove(path)
+			continue
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, digest := range strings.Fields(string(data)) {
+			leased[digest] = true
+		}
+	}
+	return leased, nil
+}
+
+// commitBlob 持有注册表锁，先把摘要加入租约，再确认 blob 已存在或调用 create 创建它，返回 blob 是否已存在。
+// 删除 blob 的一方同样持有该锁，因此要么在这之前已经删完，要么能看到租约而保留这个 blob。
+func commitBlob(lease *blobLease, digest string, create func(blob string) error) (bool, error) {
+	blob, err := blobPath(digest)
+	if err != nil {
+		return false, err
+	}
+	unlock, err := lockRegistry()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	if err := lease.hold(digest); err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(blob); err == nil {
+		return true, nil
+	}
+	return false, create(blob)
+}
+
+// saveRegistry 写入注册表，先写同目录下的临时文件再重命名，避免写一半的 models.json。
+// 调用方需持有 lockRegistry 的锁。
+func saveRegistry(models []modelInfo) error {
+	metaPath, err := registryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(models, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(metaPath, data, 0644)
+}
+
+// writeFileAtomic 先写入同目录下的唯一临时文件再重命名，
+// 读取方不会看到写了一半的文件，并发的写入也不会共用同一个临时文件
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// hashFile 计算文件的 sha256 摘要
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// storeBlob 把文件存入 blob 仓库，mode 为 importCopy、importHardlink 或 blobMove。
+// 相同内容的 blob 已存在时不会重复保存。存入的 blob 记入 lease，直到模型注册后才能被删除。
+func storeBlob(src, mode string, lease *blobLease) (modelFile, error) {
+	dir, err := blobsDir()
+	if err != nil {
+		return modelFile{}, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return modelFile{}, err
+	}
+
+	if mode == importCopy {
+		return copyToBlob(src, dir, lease)
+	}
+
+	digest, size, err := hashFile(src)
+	if err != nil {
+		return modelFile{}, err
+	}
+	file := modelFile{Digest: digest, Size: size}
+
+	existed, err := commitBlob(lease, digest, func(blob string) error {
+		switch mode {
+		case importHardlink:
+			if err := os.Link(src, blob); err != nil {
+				return fmt.Errorf("failed to hardlink %s (is it on the same filesystem? use --mode copy or symlink): %v", src, err)
+			}
+			return nil
+		case blobMove:
+			return os.Rename(src, blob)
+		}
+		return fmt.Errorf("unsupported blob store mode %q", mode)
+	})
+	if mode == blobMove && errors.Is(err, syscall.EXDEV) {
+		// 跨文件系统时退化为复制
+		if _, err := copyToBlob(src, dir, lease); err != nil {
+			return modelFile{}, err
+		}
+		os.Remove(src)
+		return file, nil
+	}
+	if err != nil {
+		return modelFile{}, err
+	}
+	if existed && mode == blobMove {
+		// 已有相同内容的 blob
+		os.Remove(src)
+	}
+	return file, nil
+}
+
+// copyToBlob 边复制边计算摘要，先写入 .partial 文件，完成后再重命名为最终的 blob
+func copyToBlob(src, dir string, lease *blobLease) (modelFile, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return modelFile{}, err
+	}
+	defer in.Close()
+
+	file, err := writeBlob(in, dir, "", lease)
+	if err != nil {
+		return modelFile{}, fmt.Errorf("failed to copy %s: %v", src, err)
+	}
+	return file, nil
+}
+
+// writeBlob 把 r 的内容写入 blob 仓库，边写边计算摘要，相同内容的 blob 已存在时丢弃本次写入。
+// expected 不为空时摘要必须与之一致，否则不保存。写入的 blob 记入 lease。
+func writeBlob(r io.Reader, dir, expected string, lease *blobLease) (modelFile, error) {
+	tmp, err := os.CreateTemp(dir, "blob-*.partial")
+	if err != nil {
+		return modelFile{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return modelFile{}, err
+	}
+
+	file := modelFile{Digest: "sha256:" + hex.EncodeToString(h.Sum(nil)), Size: size}
+	if expected != "" && file.Digest != expected {
+		return modelFile{}, fmt.Errorf("checksum mismatch: expected %s, got %s", expected, file.Digest)
+	}
+	if _, err := commitBlob(lease, file.Digest, func(blob string) error { return os.Rename(tmp.Name(), blob) }); err != nil {
+		return modelFile{}, err
+	}
+	return file, nil
+}
+
+// createModelView 为模型创建视图目录，按清单中的相对路径链接到实际文件
+func createModelView(name string, files []modelFile) (string, error) {
+	view, err := modelViewDir(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(view); err == nil {
+		return "", newError(errConflict, "model directory %s already exists", view)
+	}
+	return view, linkModelFiles(view, files)
+}
+
+// modelViewDir 返回模型视图目录
+func modelViewDir(name string) (string, error) {
+	root, err := modelsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, viewDirName(name)), nil
+}
+
+// linkModelFiles 在 view 目录下创建指向清单文件的链接，失败时删除整个目录
+func linkModelFiles(view string, files []modelFile) error {
+	for _, f := range files {
+		target, err := f.filePath()
+		if err != nil {
+			os.RemoveAll(view)
+			return err
+		}
+		link := filepath.Join(view, f.Path)
+		if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+			os.RemoveAll(view)
+			return err
+		}
+		if err := os.Symlink(target, link); err != nil {
+			os.RemoveAll(view)
+			return err
+		}
+	}
+	return nil
+}
+
+// ingestModelFiles 把 root 下的文件（相对路径 files）按导入方式存入仓库，返回模型清单。
+// 存入的 blob 记入 lease，调用方在注册模型之后释放。进度输出到 log。
+func ingestModelFiles(root string, files []string, mode string, lease *blobLease, log *slog.Logger) ([]modelFile, error) {
+	manifest := make([]modelFile, 0, len(files))
+	for _, rel := range files {
+		src := filepath.Join(root, rel)
+		infof(log, "Importing %s (%s)...", rel, mode)
+
+		var file modelFile
+		var err error
+		switch mode {
+		case importSymlink, importInPlace:
+			// 不进入 blob 仓库，只记录摘要用于校验
+			file.External = src
+			file.Digest, file.Size, err = hashFile(src)
+		default:
+			file, err = storeBlob(src, mode, lease)
+		}
+		if err != nil {
+			return nil, err
+		}
+		file.Path = filepath.ToSlash(rel)
+		manifest = append(manifest, file)
+	}
+	return manifest, nil
+}
+
+// setFiles 设置模型的文件清单，并根据清单更新模型路径、mmproj 和总大小
+func (m *modelInfo) setFiles(root string, files []modelFile) {
+	m.Files = files
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	primary, mmproj := primaryModelFiles(paths)
+
+	m.Path = root
+	if primary != "" {
+		m.Path = filepath.Join(root, primary)
+	} else if len(files) == 1 {
+		m.Path = filepath.Join(root, files[0].Path)
+	}
+	m.Mmproj = ""
+	if mmproj != "" {
+		m.Mmproj = filepath.Join(root, mmproj)
+	}
+	m.Size = 0
+	for _, f := range files {
+		m.Size += f.Size
+	}
+}
+
+// registerModel 为已入库的文件创建视图目录并写入注册表
+func registerModel(model modelInfo) (modelInfo, error) {
+	unlock, err := lockRegistry()
+	if err != nil {
+		return modelInfo{}, err
+	}
+	defer unlock()
+	models, err := loadRegistry()
+	if err != nil {
+		return modelInfo{}, err
+	}
+	for _, m := range models {
+		if m.matches(model.Name) {
+			return modelInfo{}, newError(errConflict, "model '%s' already exists", model.Name)
+		}
+	}
+
+	// inplace 模式直接使用原始路径，其余模式通过视图目录访问
+	root := model.Source
+	if model.Mode != importInPlace {
+		root, err = createModelView(model.Name, model.Files)
+		if err != nil {
+			return modelInfo{}, err
+		}
+	} else if info, err := os.Stat(root); err == nil && !info.IsDir() {
+		root = filepath.Dir(root)
+	}
+	model.setFiles(root, model.Files)
+	model.AddedDate = time.Now().UTC().Format(time.RFC3339)
+
+	models = append(models, model)
+	if err := saveRegistry(models); err != nil {
+		if model.Mode != importInPlace {
+			os.RemoveAll(root)
+		}
+		return modelInfo{}, err
+	}
+	return model, nil
+}
+
+// ensureNameAvailable 在导入或下载之前检查模型名是否已被占用
+func ensureNameAvailable(name string) error {
+	if _, err := findModel(name); err == nil {
+		return newError(errConflict, "model '%s' already exists", name)
+	} else if kindOf(err) != errNotFound {
+		return err
+	}
+	return nil
+}
+
+// updateModel 按名称或别名找到模型，用 fn 修改后写回注册表
+func updateModel(name string, fn func(models []modelInfo, model *modelInfo) error) (modelInfo, error) {
+	unlock, err := lockRegistry()
+	if err != nil {
+		return modelInfo{}, err
+	}
+	defer unlock()
+	models, err := loadRegistry()
+	if err != nil {
+		return modelInfo{}, err
+	}
+	for i := range models {
+		if !models[i].matches(name) {
+			continue
+		}
+		if err := fn(models, &models[i]); err != nil {
+			return modelInfo{}, err
+		}
+		if err := saveRegistry(models); err != nil {
+			return modelInfo{}, err
+		}
+		return models[i], nil
+	}
+	return modelInfo{}, newError(errNotFound, "model '%s' not found", name)
+}
+
+// unregisterModel 从注册表中删除模型，删除其视图目录以及不再被其他模型引用的 blob
+func unregisterModel(name string) error {
+	unlock, err := lockRegistry()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	models, err := loadRegistry()
+	if err != nil {
+		return err
+	}
+
+	var removed *modelInfo
+	remaining := make([]modelInfo, 0, len(models))
+	for i := range models {
+		if models[i].matches(name) && removed == nil {
+			removed = &models[i]
+			continue
+		}
+		remaining = append(remaining, models[i])
+	}
+	if removed == nil {
+		return newError(errNotFound, "model '%s' not found", name)
+	}
+
+	if err := saveRegistry(remaining); err != nil {
+		return err
+	}
+
+	if removed.Files == nil {
+		return removeLegacyModel(*removed)
+	}
+
+	// 删除视图目录
+	if removed.Mode != importInPlace {
+		root, err := modelsDir()
+		if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(filepath.Join(root, viewDirName(removed.Name))); err != nil {
+			return fmt.Errorf("failed to delete model directory: %v", err)
+		}
+	}
+
+	// 只删除没有其他模型引用的 blob
+	return removeUnreferencedBlobs(remaining, removed.Files)
+}
+
+// pruneBlobs 删除 candidates 中没有被任何模型引用、也不在进行中的导入租约里的 blob
+func pruneBlobs(candidates []modelFile) error {
+	unlock, err := lockRegistry()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	models, err := loadRegistry()
+	if err != nil {
+		return err
+	}
+	return removeUnreferencedBlobs(models, candidates)
+}
+
+// removeUnreferencedBlobs 删除 candidates 中不再被 models 引用的 blob，进行中的导入租约里的 blob 同样保留。
+// 调用方需持有 lockRegistry 的锁。
+func removeUnreferencedBlobs(models []modelInfo, candidates []modelFile) error {
+	leased, err := leasedBlobs()
+	if err != nil {
+		return err
+	}
+	referenced := map[string]bool{}
+	for _, m := range models {
+		for _, f := range m.Files {
+			if f.External == "" {
+				referenced[f.Digest] = true
+			}
+		}
+	}
+	for _, f := range candidates {
+		if f.External != "" || referenced[f.Digest] || leased[f.Digest] {
+			continue
+		}
+		blob, err := blobPath(f.Digest)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(blob); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete blob: %v", err)
+		}
+	}
+	return nil
+}
+
+// removeLegacyModel 删除旧版本注册的、没有文件清单的模型。
+// 只删除位于 models 目录下且不被其他模型使用的文件，不会删除共享的缓存目录。
+func removeLegacyModel(model modelInfo) error {
+	root, err := modelsDir()
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, model.Path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	if err := os.Remove(model.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete model file: %v", err)
+	}
+	// 旧版本的本地模型放在 models/<name>/ 下，目录为空时一并删除
+	os.Remove(filepath.Dir(model.Path))
+	return nil
+}
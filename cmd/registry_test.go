@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"oneinfer/client"
+)
+
+func TestViewDirName(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"unsloth/Qwen3-8B-GGUF", "unsloth--Qwen3-8B-GGUF"},
+		{"my-model", "my-model"},
+		{"ollama/llama3:8b", "ollama--llama3:8b"},
+		{"a/b--c", "a--b%2D%2Dc"},
+		{"a--b/c", "a%2D%2Db--c"},
+		{"a-/b", "a%2D--b"},
+		{"a/-b", "a--%2Db"},
+		{"100%", "100%25"},
+	}
+	for _, tt := range tests {
+		if got := viewDirName(tt.name); got != tt.want {
+			t.Errorf("viewDirName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// 不同的模型名必须对应不同的视图目录，这里枚举由 a - / % 组成的所有短名称
+func TestViewDirNameInjective(t *testing.T) {
+	seen := map[string]string{}
+	var walk func(prefix string)
+	walk = func(prefix string) {
+		if prefix != "" {
+			dir := viewDirName(prefix)
+			if other, ok := seen[dir]; ok {
+				t.Fatalf("%q and %q both map to %q", other, prefix, dir)
+			}
+			seen[dir] = prefix
+			if strings.Contains(dir, "/") {
+				t.Fatalf("viewDirName(%q) = %q contains a path separator", prefix, dir)
+			}
+		}
+		if len(prefix) == 7 {
+			return
+		}
+		for _, c := range "a-/%" {
+			walk(prefix + string(c))
+		}
+	}
+	walk("")
+}
+
+// 并发的注册和修改都不能丢失，也不能留下临时文件
+func TestRegistryConcurrentWrites(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	src := filepath.Join(home, "m.gguf")
+	os.WriteFile(src, []byte("GGUF"), 0644)
+	const n = 16
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := registerModel(modelInfo{
+				Name:     fmt.Sprintf("model-%d", i),
+				Platform: "local",
+				Source:   src,
+				Mode:     importInPlace,
+				Files:    []modelFile{{Path: "m.gguf", Digest: "sha256:" + strings.Repeat("0", 64), Size: 4, External: src}},
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := updateModel("model-0", func(models []modelInfo, m *modelInfo) error {
+				m.Labels = append(m.Labels, fmt.Sprintf("l%d", i))
+				return nil
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	models, err := loadRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != n {
+		t.Errorf("%d models registered, want %d", len(models), n)
+	}
+	for _, m := range models {
+		if m.Name == "model-0" && len(m.Labels) != n {
+			t.Errorf("model-0 has %d labels, want %d", len(m.Labels), n)
+		}
+	}
+
+	dir, _ := modelsDir()
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, "models.json")); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("models.json: %v, %v", info, err)
+	}
+}
+
+// 删除模型时，另一个导入刚去重引用、尚未注册的 blob 不能被删除
+func TestUnregisterKeepsLeasedBlobs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	src := filepath.Join(home, "m.gguf")
+	os.WriteFile(src, []byte("GGUF shared"), 0644)
+
+	model, err := importLocalModel("first", src, importCopy, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, _ := model.Files[0].filePath()
+
+	// 第二个导入复用同一个 blob，还没有注册
+	lease, err := newBlobLease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.release()
+	if _, err := storeBlob(src, importCopy, lease); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := unregisterModel("first"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(blob); err != nil {
+		t.Fatalf("leased blob was removed: %v", err)
+	}
+
+	// 租约释放后才能删除
+	lease.release()
+	if err := pruneBlobs(model.Files); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(blob); !os.IsNotExist(err) {
+		t.Errorf("unreferenced blob was kept after the lease was released: %v", err)
+	}
+}
+
+// 没有进程持有锁的租约文件是导入进程退出后的残留
+func TestLeasedBlobsIgnoresStaleLeases(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	lease, err := newBlobLease()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.release()
+	lease.hold("sha256:live")
+
+	dir, _ := leasesDir()
+	stale := filepath.Join(dir, "lease-stale")
+	os.WriteFile(stale, []byte("sha256:stale\n"), 0644)
+
+	leased, err := leasedBlobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !leased["sha256:live"] || leased["sha256:stale"] || len(leased) != 1 {
+		t.Errorf("leased = %v, want only sha256:live", leased)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale lease was not removed: %v", err)
+	}
+}
+
+// rm 和删除接口共用的检查：按名称或模型路径匹配运行中的实例
+func TestCheckModelStopped(t *testing.T) {
+	model := modelInfo{Name: "qwen", Path: "/models/qwen/model.gguf"}
+	tests := []struct {
+		name      string
+		instances []client.Instance
+		conflict  bool
+	}{
+		{"none", nil, false},
+		{"other model", []client.Instance{{ID: 1, Name: "llama", Model: "/models/llama.gguf"}}, false},
+		{"by name", []client.Instance{{ID: 2, Name: "qwen", Model: "/elsewhere.gguf"}}, true},
+		{"by path", []client.Instance{{ID: 3, Model: model.Path}}, true},
+	}
+	for _, tt := range tests {
+		err := checkModelStopped(model, tt.instances)
+		if got := kindOf(err) == errConflict; got != tt.conflict {
+			t.Errorf("%s: error = %v, want conflict %v", tt.name, err, tt.conflict)
+		}
+	}
+}
@@ -1,88 +1,67 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+
+	"oneinfer/client"
 
 	"github.com/spf13/cobra"
 )
 
+// removeOutput rm 命令的输出结构
+type removeOutput struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
 var modelRemoveCmd = &cobra.Command{
 	Use:     "remove <model_name>",
 	Aliases: []string{"rm"},
 	Short:   "Remove a model by name",
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		modelName := args[0]
-		err := removeModel(modelName)
-		if err != nil {
-			fmt.Println("Error:", err)
-			return
-		}
-		fmt.Printf("Model '%s' removed successfully!\n", modelName)
-	},
-}
-
-func init() {
-	rootCmd.AddCommand(modelRemoveCmd)
-}
-
-// removeModel 根据模型名删除模型
-func removeModel(name string) error {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return err
-	}
-
-	modelDir := filepath.Join(homeDir, ".oneinfer", "models")
-	metaPath := filepath.Join(modelDir, "models.json")
-
-	// 1. 从 models.json 文件中删除对应的模型元数据
-	var models []map[string]string
-	if _, err := os.Stat(metaPath); err == nil {
-		file, err := os.ReadFile(metaPath)
+	Args:    validArgs(cobra.ExactArgs(1)),
+	// 补全注册表中的模型名
+	ValidArgsFunction: completeModelNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 别名解析为模型名
+		model, err := findModel(args[0])
 		if err != nil {
 			return err
 		}
-
-		// 解析现有的 models.json
-		err = json.Unmarshal(file, &models)
-		if err != nil {
+		modelName := model.Name
+		// serve 没有运行时不会有运行中的实例
+		instances, err := listRunningModels()
+		if err != nil && kindOf(err) != errUnreachable {
 			return err
 		}
-
-		// 找到并删除指定模型
-		var updatedModels []map[string]string
-		for _, model := range models {
-			if model["name"] != name {
-				updatedModels = append(updatedModels, model)
-			}
-		}
-
-		// 如果模型未找到
-		if len(updatedModels) == len(models) {
-			return fmt.Errorf("model '%s' not found", name)
-		}
-
-		// 保存更新后的 models.json
-		data, err := json.MarshalIndent(updatedModels, "", "  ")
-		if err != nil {
+		if err := checkModelStopped(model, instances); err != nil {
 			return err
 		}
-
-		err = os.WriteFile(metaPath, data, 0644)
-		if err != nil {
+		if err := removeModel(modelName); err != nil {
 			return err
 		}
-	}
+		return printResult(removeOutput{Name: modelName, Status: "removed"}, func(w io.Writer) {
+			fmt.Fprintf(w, "Model '%s' removed successfully!\n", modelName)
+		})
+	},
+}
 
-	// 2. 删除模型文件
-	modelFilePath := filepath.Join(modelDir, name)
-	if err := os.RemoveAll(modelFilePath); err != nil {
-		return fmt.Errorf("failed to delete model file: %v", err)
-	}
+func init() {
+	rootCmd.AddCommand(modelRemoveCmd)
+}
 
+// checkModelStopped 模型还有运行中的实例时返回冲突错误，删除模型会移走实例正在使用的文件。
+// 实例按注册表名称或模型路径匹配，CLI 的 rm 和 serve 的删除接口都要先检查。
+func checkModelStopped(model modelInfo, instances []client.Instance) error {
+	for _, instance := range instances {
+		if instance.Name == model.Name || instance.Model == model.Path {
+			return newError(errConflict, "model '%s' is running as instance %d, stop it first", model.Name, instance.ID)
+		}
+	}
 	return nil
 }
+
+// removeModel 根据模型名删除模型，只删除不再被其他模型引用的文件
+func removeModel(name string) error {
+	return unregisterModel(name)
+}
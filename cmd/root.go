@@ -8,15 +8,33 @@ import (
 
 // rootCmd 作为 CLI 入口
 var rootCmd = &cobra.Command{
-	Use:   "OneInfer",
+	Use:   "oneinfer",
 	Short: "A CLI tool for managing AI models",
-	Long:  `OneInfer is a portable CLI tool for managing AI models like LLMs, embeddings, SD, and speech models.`,
+	Long:  "OneInfer is a portable CLI tool for managing AI models like LLMs, embeddings, SD, and speech models.\n\n" + exitCodeHelp,
+	Args:  validArgs(cobra.NoArgs),
+	// 错误统一由 Execute 按 --output 格式输出
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return validateOutputFormat()
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("Welcome to OneInfer!")
 	},
 }
 
-// Execute 运行 rootCmd
+func init() {
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputTable, "Output format: table, wide, json or yaml")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &cliError{kind: errValidation, msg: err.Error()}
+	})
+}
+
+// Execute 运行 rootCmd，出错时按 --output 格式输出错误，退出码见 ExitCode
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	if err != nil {
+		printError(err)
+	}
+	return err
 }
@@ -0,0 +1,82 @@
+# OneInfer 模型下载脚本
+#
+# 该脚本以固定内容嵌入到 oneinfer 中，所有参数都通过 stdin 上的 JSON 传入，
+# 不会拼接进脚本源码。
+import importlib
+import json
+import subprocess
+import sys
+
+LIBRARIES = {
+    "huggingface": "huggingface_hub",
+    "modelscope": "modelscope",
+}
+
+
+# 安装 pip（如果没有安装）
+def install_pip():
+    try:
+        subprocess.check_call([sys.executable, "-m", "ensurepip", "--upgrade"])
+    except subprocess.CalledProcessError:
+        print("Failed to install pip. Please install pip manually.")
+
+
+# 确保所需的库可用，仅在显式允许时才自动安装
+def ensure_library(lib, install_deps):
+    try:
+        importlib.import_module(lib)
+        return
+    except ImportError:
+        pass
+
+    if not install_deps:
+        sys.stderr.write(
+            f"Python package '{lib}' is not installed. "
+            f"Install it with 'python3 -m pip install {lib}' "
+            "or rerun with --install-deps.\n"
+        )
+        sys.exit(2)
+
+    install_pip()
+    print(f"Installing {lib}...")
+    subprocess.check_call([sys.executable, "-m", "pip", "install", lib])
+
+
+# 下载模型，token 为空时使用库自身保存的登录信息
+def download_model(platform, repo_id, revision, dest_path, file_pattern, token):
+    patterns = file_pattern or None
+    revision = revision or None
+    if platform == "huggingface":
+        from huggingface_hub import snapshot_download
+
+        snapshot_download(repo_id=repo_id, revision=revision, local_dir=dest_path, allow_patterns=patterns, token=token or None)
+    elif platform == "modelscope":
+        from modelscope import snapshot_download
+
+        if token:
+            from modelscope.hub.api import HubApi
+
+            HubApi().login(token)
+        snapshot_download(repo_id, revision=revision, local_dir=dest_path, allow_file_pattern=patterns)
+
+
+def main():
+    params = json.load(sys.stdin)
+    platform = params["platform"]
+    if platform not in LIBRARIES:
+        sys.stderr.write(f"Unsupported platform: {platform}\n")
+        sys.exit(2)
+
+    ensure_library(LIBRARIES[platform], params.get("install_deps", False))
+    download_model(
+        platform,
+        params["repo_id"],
+        params.get("revision", ""),
+        params["dest_path"],
+        params.get("file_pattern", ""),
+        params.get("token", ""),
+    )
+
+
+if __name__ == "__main__":
+    main()
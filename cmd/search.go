@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// GGUF 文件名中的量化类型，如 Q4_K_M、IQ2_XXS、Q8_0、BF16
+var quantPattern = regexp.MustCompile(`(?i)(?:^|[-_.])((?:I?Q[1-8](?:_[0-9KXSML]+)*)|BF16|F16|F32|MXFP4)(?:[-_.]|$)`)
+
+// hubRepo 搜索结果中的一个仓库
+type hubRepo struct {
+	ID        string `json:"id"`
+	Downloads int64  `json:"downloads"`
+	Likes     int64  `json:"likes"`
+	GGUF      bool   `json:"gguf"`
+	Updated   string `json:"updated,omitempty"`
+}
+
+// hubFile 仓库中的一个文件
+type hubFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Quant  string `json:"quant,omitempty"`
+	Digest string `json:"digest,omitempty"`
+}
+
+// searchOutput search 命令的输出结构
+type searchOutput struct {
+	Platform string    `json:"platform"`
+	Query    string    `json:"query"`
+	Repos    []hubRepo `json:"repos"`
+}
+
+// filesOutput files 命令的输出结构
+type filesOutput struct {
+	Platform string    `json:"platform"`
+	Repo     string    `json:"repo"`
+	Revision string    `json:"revision"`
+	Files    []hubFile `json:"files"`
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search Hugging Face or ModelScope for model repositories",
+	Args:  validArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		platform, _ := cmd.Flags().GetString("platform")
+		limit, _ := cmd.Flags().GetInt("limit")
+		ggufOnly, _ := cmd.Flags().GetBool("gguf")
+		if !isSupportedPlatform(platform) {
+			return newError(errValidation, "unsupported platform %q: expected one of %s", platform, strings.Join(supportedPlatforms, ", "))
+		}
+		if limit <= 0 {
+			return newError(errValidation, "--limit must be greater than 0")
+		}
+
+		repos, err := searchHub(platform, args[0], limit, ggufOnly)
+		if err != nil {
+			return err
+		}
+		out := searchOutput{Platform: platform, Query: args[0], Repos: repos}
+
+		return printResult(out, func(w io.Writer) {
+			if len(out.Repos) == 0 {
+				fmt.Fprintf(w, "No repositories matching %q found on %s.\n", out.Query, out.Platform)
+				return
+			}
+			t := &table{
+				headers:     []string{"REPO", "DOWNLOADS", "GGUF"},
+				wideHeaders: []string{"LIKES", "UPDATED"},
+			}
+			for _, r := range out.Repos {
+				gguf := "no"
+				if r.GGUF {
+					gguf = "yes"
+				}
+				t.addRow([]string{r.ID, strconv.FormatInt(r.Downloads, 10), gguf},
+					strconv.FormatInt(r.Likes, 10), r.Updated)
+			}
+			t.write(w)
+		})
+	},
+}
+
+var filesCmd = &cobra.Command{
+	Use:   "files <repo_id[@revision]>",
+	Short: "List the files in a Hugging Face or ModelScope repository with sizes and quantization types",
+	Args:  validArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		platform, _ := cmd.Flags().GetString("platform")
+		ggufOnly, _ := cmd.Flags().GetBool("gguf")
+
+		repoID, revision := splitRevision(args[0])
+		if err := validateDownload(platform, repoID, ""); err != nil {
+			return err
+		}
+		if revision == "" {
+			revision = defaultRevisions[platform]
+		}
+		if err := validateRevision(revision); err != nil {
+			return err
+		}
+
+		files, err := listHubFiles(platform, repoID, revision)
+		if err != nil {
+			return err
+		}
+		out := filesOutput{Platform: platform, Repo: repoID, Revision: revision, Files: []hubFile{}}
+		for _, f := range files {
+			if ggufOnly && !strings.HasSuffix(strings.ToLower(f.Path), ".gguf") {
+				continue
+			}
+			out.Files = append(out.Files, f)
+		}
+
+		return printResult(out, func(w io.Writer) {
+			if len(out.Files) == 0 {
+				fmt.Fprintf(w, "No files found in %s@%s.\n", out.Repo, out.Revision)
+				return
+			}
+			t := &table{
+				headers:     []string{"FILE", "SIZE", "QUANT"},
+				wideHeaders: []string{"DIGEST"},
+			}
+			for _, f := range out.Files {
+				t.addRow([]string{f.Path, formatBytes(f.Size), f.Quant}, f.Digest)
+			}
+			t.write(w)
+		})
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{searchCmd, filesCmd} {
+		c.Flags().StringP("platform", "p", "huggingface", "Platform to query: huggingface or modelscope")
+		c.RegisterFlagCompletionFunc("platform", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return supportedPlatforms, cobra.ShellCompDirectiveNoFileComp
+		})
+		rootCmd.AddCommand(c)
+	}
+	searchCmd.Flags().Int("limit", 20, "Maximum number of repositories to show")
+	searchCmd.Flags().Bool("gguf", false, "Only show repositories with GGUF files")
+	filesCmd.Flags().Bool("gguf", false, "Only show GGUF files")
+}
+
+// quantLabel 从文件名中解析量化类型，无法识别时返回空
+func quantLabel(name string) string {
+	base := path.Base(name)
+	if !strings.HasSuffix(strings.ToLower(base), ".gguf") {
+		return ""
+	}
+	base = shardPattern.ReplaceAllString(base, ".gguf")
+	m := quantPattern.FindAllStringSubmatch(strings.TrimSuffix(base, path.Ext(base)), -1)
+	if m == nil {
+		return ""
+	}
+	// 取最后一个匹配，避免把模型名中的片段（如 Qwen2）误认为量化类型
+	return strings.ToUpper(m[len(m)-1][1])
+}
+
+// searchHub 按名称搜索平台上的模型仓库，按下载量排序
+func searchHub(platform, query string, limit int, ggufOnly bool) ([]hubRepo, error) {
+	repos := []hubRepo{}
+	switch platform {
+	case "huggingface":
+		var resp []struct {
+			ID           string   `json:"id"`
+			Downloads    int64    `json:"downloads"`
+			Likes        int64    `json:"likes"`
+			Tags         []string `json:"tags"`
+			LibraryName  string   `json:"library_name"`
+			LastModified string   `json:"lastModified"`
+		}
+		q := url.Values{
+			"search":    {query},
+			"limit":     {strconv.Itoa(limit)},
+			"sort":      {"downloads"},
+			"direction": {"-1"},
+		}
+		if ggufOnly {
+			q.Set("filter", "gguf")
+		}
+		if err := hubGet(platform, "/api/models", q, &resp); err != nil {
+			return nil, err
+		}
+		for _, m := range resp {
+			gguf := m.LibraryName == "gguf"
+			for _, tag := range m.Tags {
+				if tag == "gguf" {
+					gguf = true
+				}
+			}
+			repos = append(repos, hubRepo{ID: m.ID, Downloads: m.Downloads, Likes: m.Likes, GGUF: gguf, Updated: m.LastModified})
+		}
+
+	case "modelscope":
+		var resp struct {
+			Code int `json:"Code"`
+			Data struct {
+				Model struct {
+					Models []struct {
+						Name            string   `json:"Name"`
+						Path            string   `json:"Path"`
+						Downloads       int64    `json:"Downloads"`
+						Stars           int64    `json:"Stars"`
+						Libraries       []string `json:"Libraries"`
+						Tags            []string `json:"Tags"`
+						LastUpdatedTime int64    `json:"LastUpdatedTime"`
+					} `json:"Models"`
+				} `json:"Model"`
+			} `json:"Data"`
+			Message string `json:"Message"`
+		}
+		body := map[string]interface{}{
+			"PageSize":   limit,
+			"PageNumber": 1,
+			"SortBy":     "DownloadsCount",
+			"Target":     "",
+			"Criterion":  []interface{}{},
+			"Name":       query,
+		}
+		if err := hubRequest(http.MethodPut, platform, "/api/v1/dolphin/models", nil, body, &resp); err != nil {
+			return nil, err
+		}
+		if resp.Code != 0 && resp.Code != 200 {
+			return nil, fmt.Errorf("modelscope: %s", resp.Message)
+		}
+		for _, m := range resp.Data.Model.Models {
+			// ModelScope 没有 GGUF 过滤条件，根据库、标签和仓库名判断
+			gguf := strings.Contains(strings.ToLower(m.Name), "gguf")
+			for _, tag := range append(m.Libraries, m.Tags...) {
+				if strings.EqualFold(tag, "gguf") {
+					gguf = true
+				}
+			}
+			if ggufOnly && !gguf {
+				continue
+			}
+			repo := hubRepo{ID: m.Path + "/" + m.Name, Downloads: m.Downloads, Likes: m.Stars, GGUF: gguf}
+			if m.LastUpdatedTime > 0 {
+				repo.Updated = time.Unix(m.LastUpdatedTime, 0).UTC().Format(time.RFC3339)
+			}
+			repos = append(repos, repo)
+		}
+
+	default:
+		return nil, newError(errValidation, "unsupported platform %q", platform)
+	}
+	return repos, nil
+}
+
+// listHubFiles 列出仓库在某个 revision 下的所有文件，按路径排序
+func listHubFiles(platform, repoID, revision string) ([]hubFile, error) {
+	var files []hubFile
+	switch platform {
+	case "huggingface":
+		var resp []struct {
+			Type string `json:"type"`
+			Path string `json:"path"`
+			Size int64  `json:"size"`
+			LFS  *struct {
+				Oid  string `json:"oid"`
+				Size int64  `json:"size"`
+			} `json:"lfs"`
+		}
+		q := url.Values{"recursive": {"true"}}
+		if err := hubGet(platform, "/api/models/"+repoID+"/tree/"+url.PathEscape(revision), q, &resp); err != nil {
+			return nil, err
+		}
+		for _, f := range resp {
+			if f.Type != "file" {
+				continue
+			}
+			file := hubFile{Path: f.Path, Size: f.Size}
+			if f.LFS != nil {
+				file.Size = f.LFS.Size
+				file.Digest = "sha256:" + f.LFS.Oid
+			}
+			files = append(files, file)
+		}
+
+	case "modelscope":
+		msFiles, err := listModelScopeFiles(repoID, revision)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range msFiles {
+			file := hubFile{Path: f.Path, Size: f.Size}
+			if f.Sha256 != "" {
+				file.Digest = "sha256:" + f.Sha256
+			}
+			files = append(files, file)
+		}
+
+	default:
+		return nil, newError(errValidation, "unsupported platform %q", platform)
+	}
+
+	for i := range files {
+		files[i].Quant = quantLabel(files[i].Path)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
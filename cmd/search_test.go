@@ -0,0 +1,29 @@
+package cmd
+
+import "testing"
+
+func TestQuantLabel(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Qwen3-8B-Q4_K_M.gguf", "Q4_K_M"},
+		{"qwen3-8b-q4_k_m.gguf", "Q4_K_M"},
+		{"Qwen2.5-7B-Instruct-Q8_0.gguf", "Q8_0"},
+		{"Qwen2-7B-IQ2_XXS.gguf", "IQ2_XXS"},
+		{"model.BF16.gguf", "BF16"},
+		{"model-f16.gguf", "F16"},
+		{"gpt-oss-20b-MXFP4.gguf", "MXFP4"},
+		{"sub/dir/Llama-3-70B-Q5_K_S.gguf", "Q5_K_S"},
+		{"Qwen3-235B-Q4_K_M-00001-of-00003.gguf", "Q4_K_M"},
+		{"Qwen3-235B-Q4_K_M-00003-of-00003.gguf", "Q4_K_M"},
+		{"Q4_K_M/Qwen3-235B-Q4_K_M-00002-of-00003.gguf", "Q4_K_M"},
+		// 模型名中的 Q2、Q3 等不是量化类型
+		{"Qwen2-7B.gguf", ""},
+		{"Qwen3-8B-Q4_K_M.bin", ""},
+		{"README.md", ""},
+		{"model.gguf", ""},
+	}
+	for _, tt := range tests {
+		if got := quantLabel(tt.name); got != tt.want {
+			t.Errorf("quantLabel(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -4,36 +4,59 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"os/signal"
+	"sort"
 	"strconv"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/spf13/cobra"
 )
 
-// 进程信息结构体
+// 进程信息结构体，ID 由 allocInstanceID 分配，自动重启后保持不变
 type ModelProcess struct {
-	ID      int    `json:"id"`
-	Model   string `json:"model"`
-	Status  string `json:"status"`
-	Host    string `json:"host"`
-	Port    int    `json:"port"`
-	Command *exec.Cmd
+	ID       int      `json:"id"`
+	Model    string   `json:"model"`
+	Name     string   `json:"name,omitempty"`
+	Status   string   `json:"status"`
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Restarts int      `json:"restarts"`
+	LogPath  string   `json:"-"`
+	Args     []string `json:"-"`
+	Command  *exec.Cmd
+	stopping bool
+	exited   bool // Command 已退出，可能尚未被回收
+
+	// 当前进程的启动时间和最近一次资源采样，用于计算运行时长和 CPU 占用率
+	started    time.Time
+	cpuSample  float64
+	sampledAt  time.Time
+	cpuPercent float64
+	stats      *procStats // 尚未采样时为 nil
 }
 
+// ModelProcessStatus 实例的状态和资源占用，资源从 /proc 按进程组统计
 type ModelProcessStatus struct {
-	ID     int    `json:"id"`
-	Model  string `json:"model"`
-	Status string `json:"status"`
-	Host   string `json:"host"`
-	Port   int    `json:"port"`
+	ID            int     `json:"id"`
+	Model         string  `json:"model"`
+	Name          string  `json:"name,omitempty"`
+	Status        string  `json:"status"`
+	Host          string  `json:"host"`
+	Port          int     `json:"port"`
+	Restarts      int     `json:"restarts"`
+	UptimeSeconds int64   `json:"uptime_seconds"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	RSSBytes      int64   `json:"rss_bytes"`
+	Threads       int     `json:"threads"`
+	Connections   int     `json:"connections"`
 }
 
 var (
@@ -41,6 +64,22 @@ var (
 	modelMux = sync.Mutex{}
 )
 
+// defaultListenAddr serve 默认只监听本机，需要从其他主机访问时用 --listen 指定
+const defaultListenAddr = "127.0.0.1:9090"
+
+// isLoopbackAddr 判断监听地址是否只接受本机连接
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 //go:embed static/*
 var staticFiles embed.FS
 
@@ -56,6 +95,12 @@ func serveStaticFiles(router *mux.Router) {
 		w.Write(data)
 	})
 
+	// Chat playground
+	router.HandleFunc("/chat", serveChatPage)
+
+	// Model library management, the page uses the /api/v1/models REST API
+	router.HandleFunc("/manage", serveLibraryPage)
+
 	// Serve the rest of the static files
 	fs := http.FileServer(http.FS(staticFiles))
 	router.PathPrefix("/static/").Handler(http.StripPrefix("/static", fs))
@@ -65,24 +110,110 @@ func serveStaticFiles(router *mux.Router) {
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Start the oneinfer model management service",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Starting oneinfer service on http://0.0.0.0:9090...")
+	Long: `Start the oneinfer model management service, on 127.0.0.1:9090 by default.
+
+Use --listen 0.0.0.0:9090 to make the web UI and the API reachable from other hosts. When
+ONEINFER_API_TOKEN is set, requests that change anything (every method except GET and HEAD) must
+send it as "Authorization: Bearer <token>"; the CLI sends the same variable.
+
+Requests that change anything are refused when a browser sends them from another site, and
+their bodies must be JSON. On a loopback address only requests for localhost are accepted.
+
+Logs are written to ~/.oneinfer/logs/serve.log (rotated by size) and also to the terminal when
+stderr is one, so the service can be debugged when run under nohup or systemd.`,
+	Args: validArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts logOptions
+		opts.Level, _ = cmd.Flags().GetString("log-level")
+		opts.Format, _ = cmd.Flags().GetString("log-format")
+		opts.File, _ = cmd.Flags().GetString("log-file")
+		opts.MaxSize, _ = cmd.Flags().GetInt("log-max-size")
+		opts.MaxBackups, _ = cmd.Flags().GetInt("log-max-backups")
+		logFile, err := setupLogging(opts)
+		if err != nil {
+			return err
+		}
+		defer logFile.Close()
+
+		maxDownloads, _ := cmd.Flags().GetInt("max-downloads")
+		if maxDownloads < 1 {
+			return newError(errValidation, "--max-downloads must be at least 1")
+		}
+		addr, _ := cmd.Flags().GetString("listen")
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return newError(errValidation, "invalid --listen address %q: expected host:port", addr)
+		}
+		token := os.Getenv("ONEINFER_API_TOKEN")
+
+		serveStarted = time.Now()
 		router := mux.NewRouter()
-		router.HandleFunc("/models", listModelsHandler).Methods("GET")
-		router.HandleFunc("/models", startModelHandler).Methods("POST")
-		router.HandleFunc("/models/{id}", stopModelHandler).Methods("DELETE")
-		router.HandleFunc("/stop", stopServerHandler).Methods("POST")
+		registerAPIRoutes(router)
 		router.HandleFunc("/health", healthCheckHandler).Methods("GET")
-		router.HandleFunc("/list", listAllModelHandler).Methods("GET")
+		router.HandleFunc("/metrics", metricsHandler).Methods("GET")
+
+		// 旧的未版本化接口，保留一个弃用周期
+		instances := apiPrefix + "/instances"
+		router.HandleFunc("/models", deprecatedRoute("/models", instances, listModelsHandler)).Methods("GET")
+		router.HandleFunc("/models", deprecatedRoute("/models", instances, startModelHandler)).Methods("POST")
+		router.HandleFunc("/models/{id}", deprecatedRoute("/models", instances, stopModelHandler)).Methods("DELETE")
+		router.HandleFunc("/models/{id}/logs", deprecatedRoute("/models", instances, modelLogsHandler)).Methods("GET")
+		router.HandleFunc("/models/{id:[0-9]+}/v1/{path:.*}", deprecatedRoute("/models", instances, modelProxyHandler))
+		router.HandleFunc("/stop", deprecatedRoute("/stop", apiPrefix+"/daemon/shutdown", stopServerHandler)).Methods("POST")
+		router.HandleFunc("/list", deprecatedRoute("/list", apiPrefix+"/models", listAllModelHandler)).Methods("GET")
+		router.HandleFunc("/events", deprecatedRoute("/events", apiPrefix+"/events", eventsHandler)).Methods("GET")
+		router.HandleFunc("/events", deprecatedRoute("/events", apiPrefix+"/events", postEventHandler)).Methods("POST")
+		router.Use(accessLogMiddleware)
+		router.Use(metricsMiddleware)
+		router.Use(browserGuardMiddleware(isLoopbackAddr(addr)))
+		router.Use(tokenMiddleware(token))
+		router.NotFoundHandler = accessLogMiddleware(http.NotFoundHandler())
 
 		// 绑定静态文件
 		serveStaticFiles(router)
 
-		log.Fatal(http.ListenAndServe(":9090", router))
+		// 注册表由 CLI 进程直接修改，定期检查并推送变化
+		go watchRegistry()
+		// 把事件投递给配置的 webhook
+		go runWebhooks()
+		// 定期采样实例的资源占用
+		go sampleInstances()
+
+		slog.Info("starting oneinfer service", "addr", "http://"+addr, "token_required", token != "")
+		if !isLoopbackAddr(addr) && token == "" {
+			slog.Warn("the API is reachable from other hosts without authentication, set ONEINFER_API_TOKEN to require a token for changes", "addr", addr)
+		}
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			slog.Error("oneinfer service stopped", "error", err)
+			return err
+		}
+		// 端口监听成功、确认没有其他 serve 在运行后，再恢复上次未完成的下载任务
+		if err := startDownloadJobs(maxDownloads); err != nil {
+			return err
+		}
+		go suspendOnSignal()
+		if err := http.Serve(listener, router); err != nil {
+			slog.Error("oneinfer service stopped", "error", err)
+			return err
+		}
+		return nil
 	},
 }
 
 func init() {
+	serveCmd.Flags().String("log-level", "info", "Log level: debug, info, warn or error")
+	serveCmd.Flags().String("log-format", "text", "Log format: text or json")
+	serveCmd.Flags().String("log-file", "", "Log file (default ~/.oneinfer/logs/serve.log)")
+	serveCmd.Flags().Int("log-max-size", defaultLogMaxSize, "Rotate the log file when it reaches this size in MB")
+	serveCmd.Flags().Int("log-max-backups", defaultLogMaxBackups, "Number of rotated log files to keep")
+	serveCmd.Flags().Int("max-downloads", defaultMaxDownloads, "Number of background downloads that run at the same time")
+	serveCmd.Flags().String("listen", defaultListenAddr, "Address to listen on, e.g. 0.0.0.0:9090 to accept connections from other hosts")
+	serveCmd.RegisterFlagCompletionFunc("log-level", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"debug", "info", "warn", "error"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	serveCmd.RegisterFlagCompletionFunc("log-format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp
+	})
 	rootCmd.AddCommand(serveCmd)
 }
 
@@ -91,96 +222,129 @@ func listModelsHandler(w http.ResponseWriter, r *http.Request) {
 	modelMux.Lock()
 	defer modelMux.Unlock()
 
-	modelList := make([]ModelProcess, 0, len(models))
-	for _, mp := range models {
-		modelList = append(modelList, *mp)
-	}
-
-	// 输出日志确认是否获取到模型数据
-	fmt.Printf("Returning models: %v\n", modelList)
+	requestLogger(r).Debug("listing running models", "count", len(models))
 
 	// 创建一个新的切片，用来存储可序列化的模型数据
 	serializableModels := make([]ModelProcessStatus, 0, len(models))
 	for _, mp := range models {
-		// 只取出模型的可序列化字段
-		serializableModels = append(serializableModels, ModelProcessStatus{
-			ID:     mp.ID,
-			Model:  mp.Model,
-			Host:   mp.Host,
-			Port:   mp.Port,
-			Status: mp.Status,
-		})
+		serializableModels = append(serializableModels, mp.status())
 	}
+	sort.Slice(serializableModels, func(i, j int) bool { return serializableModels[i].ID < serializableModels[j].ID })
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(serializableModels)
 }
 
-// 启动一个模型进程（分离进程）
+// 启动一个模型进程（分离进程），返回全部运行中的模型
 func startModelHandler(w http.ResponseWriter, r *http.Request) {
 	modelMux.Lock()
 	defer modelMux.Unlock()
 
+	if startInstance(w, r) == nil {
+		return
+	}
+
+	// 创建一个新的切片，用来存储可序列化的模型数据
+	serializableModels := make([]ModelProcessStatus, 0, len(models))
+	for _, mp := range models {
+		serializableModels = append(serializableModels, mp.status())
+	}
+
+	// 返回成功响应
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(serializableModels)
+}
+
+// startInstance 按请求启动模型进程，失败时写入错误响应并返回 nil。调用方需持有 modelMux。
+func startInstance(w http.ResponseWriter, r *http.Request) *ModelProcess {
 	// 解析 JSON 请求
 	var req struct {
-		Model string `json:"model"`
-		Host  string `json:"host"`
-		Port  int    `json:"port"`
+		Model  string `json:"model"`
+		Mmproj string `json:"mmproj"`
+		Name   string `json:"name"`
+		Host   string `json:"host"`
+		Port   int    `json:"port"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
+		return nil
+	}
+	logger := requestLogger(r)
+	if req.Model == "" {
+		http.Error(w, "model is required", http.StatusBadRequest)
+		return nil
+	}
+	if req.Port < 1 || req.Port > 65535 {
+		http.Error(w, fmt.Sprintf("invalid port %d", req.Port), http.StatusBadRequest)
+		return nil
+	}
+
+	// 运行 Llama.cpp 进程（独立进程）
+	args := []string{"--host", req.Host, "--port", strconv.Itoa(req.Port), "--model", req.Model, "-ngl", "9999"}
+	if req.Mmproj != "" {
+		args = append(args, "--mmproj", req.Mmproj)
+	}
+	// 旧版客户端不传注册表名称，按模型路径查找
+	name := req.Name
+	if name == "" {
+		if registered, err := listModels(); err == nil {
+			for _, m := range registered {
+				if m.Path == req.Model {
+					name = m.Name
+					break
+				}
+			}
+		}
+	}
+	modelProcess := &ModelProcess{
+		Model: req.Model,
+		Name:  name,
+		Host:  req.Host,
+		Port:  req.Port,
+		Args:  args,
 	}
 
 	// 检查端口是否已被占用
 	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", req.Host, req.Port))
 	if err != nil {
+		logger.Warn("port already in use", "host", req.Host, "port", req.Port)
+		refuseModel(modelProcess, fmt.Sprintf("port %d is already in use", req.Port))
 		http.Error(w, fmt.Sprintf("Port %d is already in use", req.Port), http.StatusConflict)
-		return
+		return nil
 	}
 	listener.Close() // 关闭监听器，因为只是做占用检测
 
-	// 运行 Llama.cpp 进程（独立进程）
-	serverPath := "/usr/local/oneinfer/llama/llama-server"
-	cmd := exec.Command(serverPath, "--host", req.Host, "--port", strconv.Itoa(req.Port), "--model", req.Model, "-ngl", "9999")
-
-	// 分离进程，不让 serve 进程被阻塞
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Start(); err != nil {
-		http.Error(w, "Failed to start model", http.StatusInternalServerError)
-		return
+	// 模型进程的输出写入日志文件，可通过 `oneinfer logs` 查看
+	logFile, err := createInstanceLog()
+	if err != nil {
+		logger.Error("failed to create model log file", "error", err)
+		refuseModel(modelProcess, err.Error())
+		http.Error(w, "Failed to create model log file", http.StatusInternalServerError)
+		return nil
 	}
+	defer logFile.Close()
 
-	// 记录进程信息
-	modelProcess := &ModelProcess{
-		ID:      cmd.Process.Pid,
-		Model:   req.Model,
-		Host:    req.Host,
-		Port:    req.Port,
-		Command: cmd,
+	if err := spawnModel(modelProcess, logFile); err != nil {
+		os.Remove(logFile.Name())
+		logger.Error("failed to start model", "model", req.Model, "error", err)
+		refuseModel(modelProcess, err.Error())
+		http.Error(w, "Failed to start model", http.StatusInternalServerError)
+		return nil
 	}
-	models[cmd.Process.Pid] = modelProcess
 
-	// 创建一个新的切片，用来存储可序列化的模型数据
-	serializableModels := make([]ModelProcessStatus, 0, len(models))
-	for _, mp := range models {
-		// 只取出模型的可序列化字段
-		serializableModels = append(serializableModels, ModelProcessStatus{
-			ID:     mp.ID,
-			Model:  mp.Model,
-			Host:   mp.Host,
-			Port:   mp.Port,
-			Status: mp.Status,
-		})
+	// 日志文件以实例 ID 命名
+	modelProcess.ID = allocInstanceID()
+	modelProcess.LogPath = instanceLogPath(logFile, modelProcess.ID)
+	if err := os.Rename(logFile.Name(), modelProcess.LogPath); err != nil {
+		modelProcess.LogPath = logFile.Name()
 	}
 
-	// 返回成功响应
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(serializableModels)
+	// 记录进程信息
+	models[modelProcess.ID] = modelProcess
+	logger.Info("model started", "id", modelProcess.ID, "model", modelProcess.displayName(), "host", req.Host, "port", req.Port, "log", modelProcess.LogPath)
+	publishModelEvent(eventModelStarted, modelProcess, "", 0)
+	return modelProcess
 }
 
 // 停止模型进程
@@ -188,45 +352,86 @@ func stopModelHandler(w http.ResponseWriter, r *http.Request) {
 	modelMux.Lock()
 	defer modelMux.Unlock()
 
+	if mp := stopInstance(w, r); mp != nil {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Stopped process %d\n", mp.ID)
+	}
+}
+
+// stopInstance 停止路径中 {id} 指定的模型进程，失败时写入错误响应并返回 nil。调用方需持有 modelMux。
+func stopInstance(w http.ResponseWriter, r *http.Request) *ModelProcess {
 	vars := mux.Vars(r)
 	pid, err := strconv.Atoi(vars["id"])
 	if err != nil {
 		http.Error(w, "Invalid process ID", http.StatusBadRequest)
-		return
+		return nil
 	}
 
 	modelProcess, exists := models[pid]
 	if !exists {
 		http.Error(w, "Process not found", http.StatusNotFound)
-		return
+		return nil
 	}
 
 	// 终止进程
-	if err := syscall.Kill(-modelProcess.ID, syscall.SIGKILL); err != nil {
+	if err := stopModelProcess(modelProcess); err != nil {
+		requestLogger(r).Error("failed to stop model", "id", pid, "error", err)
 		http.Error(w, "Failed to stop process", http.StatusInternalServerError)
-		return
+		return nil
 	}
 
 	delete(models, pid)
-
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "Stopped process %d\n", pid)
+	requestLogger(r).Info("model stopped", "id", pid, "model", modelProcess.displayName())
+	modelProcess.Status = statusStopped
+	publishModelEvent(eventModelStopped, modelProcess, "", 0)
+	return modelProcess
 }
 
 // 关闭 `serve` 并停止所有模型
 func stopServerHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("Stopping oneinfer service...")
+	stopAllModels(r)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+	exitAfterResponse(w)
+}
+
+// stopAllModels 停止所有模型进程
+func stopAllModels(r *http.Request) {
+	logger := requestLogger(r)
+	logger.Info("stopping oneinfer service")
 
-	// 停止所有模型进程
 	modelMux.Lock()
 	for pid, model := range models {
-		fmt.Printf("Stopping model %s (PID %d)...\n", model.Model, pid)
-		syscall.Kill(-pid, syscall.SIGKILL) // 终止进程组
+		logger.Info("stopping model", "id", pid, "model", model.displayName())
+		stopModelProcess(model) // 终止进程组
 		delete(models, pid)
+		model.Status = statusStopped
+		publishModelEvent(eventModelStopped, model, "", 0)
 	}
 	modelMux.Unlock()
 
-	fmt.Println("All models stopped. Exiting...")
+	logger.Info("all models stopped, exiting")
+}
+
+// exitAfterResponse 先把响应发给客户端，中断后台下载任务，再退出进程
+func exitAfterResponse(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		suspendDownloadJobs()
+		os.Exit(0)
+	}()
+}
+
+// suspendOnSignal 收到 SIGTERM 或 Ctrl-C 时与 daemon/shutdown 一样先中断下载任务再退出，任务在下次启动时续传
+func suspendOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	sig := <-signals
+	slog.Info("received signal, suspending downloads and exiting", "signal", sig.String())
+	suspendDownloadJobs()
 	os.Exit(0)
 }
 
@@ -238,47 +443,16 @@ func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 
 // 列出本地所有模型
 func listAllModelHandler(w http.ResponseWriter, r *http.Request) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		http.Error(w, "Failed to get user home directory", http.StatusInternalServerError)
-		return
-	}
-
-	modelDir := filepath.Join(homeDir, ".oneinfer", "models")
-	metaPath := filepath.Join(modelDir, "models.json")
-
-	// 读取现有的 models.json
-	if _, err := os.Stat(metaPath); err != nil {
-		if os.IsNotExist(err) {
-			http.Error(w, "No models found.", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to read models.json", http.StatusInternalServerError)
-		return
-	}
-
-	// 解析 models.json 文件
-	var models []map[string]string
-	file, err := os.ReadFile(metaPath)
+	models, err := listModels()
 	if err != nil {
+		requestLogger(r).Error("failed to read models.json", "error", err)
 		http.Error(w, "Failed to read models.json", http.StatusInternalServerError)
 		return
 	}
-	err = json.Unmarshal(file, &models)
-	if err != nil {
-		http.Error(w, "Failed to parse models.json", http.StatusInternalServerError)
-		return
-	}
-
-	// 创建一个新的切片，用来存储可序列化的模型数据
-	serializableModels := make([]map[string]string, 0, len(models))
-	for _, model := range models {
-		serializableModels = append(serializableModels, model)
-	}
 
 	// 返回模型数据
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(serializableModels); err != nil {
+	if err := json.NewEncoder(w).Encode(models); err != nil {
 		http.Error(w, "Failed to encode models", http.StatusInternalServerError)
 	}
 }
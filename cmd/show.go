@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// showOutput show 命令的输出结构
+type showOutput struct {
+	modelInfo
+	Exists bool `json:"exists"`
+}
+
+var showCmd = &cobra.Command{
+	Use:               "show <model_name>",
+	Short:             "Show details of an added model",
+	Args:              validArgs(cobra.ExactArgs(1)),
+	ValidArgsFunction: completeModelNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		model, err := findModel(args[0])
+		if err != nil {
+			return err
+		}
+
+		out := showOutput{modelInfo: model}
+		if _, err := os.Stat(model.Path); err == nil {
+			out.Exists = true
+		}
+
+		return printResult(out, func(w io.Writer) {
+			t := &table{headers: []string{"FIELD", "VALUE"}}
+			t.addRow([]string{"Name", out.Name})
+			t.addRow([]string{"Platform", out.Platform})
+			if out.Revision != "" {
+				t.addRow([]string{"Revision", out.Revision})
+				t.addRow([]string{"Commit", out.Commit})
+			}
+			t.addRow([]string{"Aliases", strings.Join(out.Aliases, ", ")})
+			t.addRow([]string{"Labels", strings.Join(out.Labels, ", ")})
+			t.addRow([]string{"Path", out.Path})
+			if out.Defaults != nil {
+				t.addRow([]string{"Default Host", out.Defaults.Host})
+				t.addRow([]string{"Default Port", strconv.Itoa(out.Defaults.Port)})
+			}
+			t.addRow([]string{"Exists", strconv.FormatBool(out.Exists)})
+			t.addRow([]string{"Size", formatBytes(out.Size)})
+			t.write(w)
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+}
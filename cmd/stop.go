@@ -1,35 +1,48 @@
 package cmd
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"log"
-	"net/http"
-	"os"
-	"strconv"
+	"io"
 
 	"github.com/spf13/cobra"
 )
 
+// stopOutput stop 命令的输出结构
+type stopOutput struct {
+	Target string `json:"target"`
+	ID     int    `json:"id,omitempty"`
+	Status string `json:"status"`
+}
+
 var stopCmd = &cobra.Command{
-	Use:   "stop",
+	Use:   "stop <model_id|model_name|serve>",
 	Short: "Stop a model or the entire service",
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) == 0 {
-			log.Fatal("Model ID or 'serve' is required")
-		}
-
+	Args:  validArgs(cobra.ExactArgs(1)),
+	// 补全运行中的实例 ID、模型名和别名以及 serve
+	ValidArgsFunction: completeStopArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// 如果参数是 "serve"，则停止整个服务
 		if args[0] == "serve" {
-			stopServer()
-		} else {
-			// 否则尝试停止指定模型
-			modelID, err := strconv.Atoi(args[0])
-			if err != nil {
-				log.Fatalf("Invalid model ID: %v", err)
+			if err := stopServer(); err != nil {
+				return err
 			}
-			stopModel(modelID)
+			return printResult(stopOutput{Target: "serve", Status: "stopped"}, func(w io.Writer) {
+				fmt.Fprintln(w, "Server stopped successfully")
+			})
+		}
+
+		// 否则按实例 ID、模型名或别名停止指定模型
+		modelID, err := resolveInstanceID(args[0])
+		if err != nil {
+			return err
+		}
+		if err := stopModel(modelID); err != nil {
+			return err
 		}
+		return printResult(stopOutput{Target: "model", ID: modelID, Status: "stopped"}, func(w io.Writer) {
+			fmt.Fprintf(w, "Model %d stopped successfully\n", modelID)
+		})
 	},
 }
 
@@ -38,48 +51,12 @@ func init() {
 }
 
 // 停止指定模型的进程
-func stopModel(modelID int) {
-	url := fmt.Sprintf("http://localhost:9090/models/%d", modelID)
-
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		log.Fatalf("Error creating DELETE request: %v", err)
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Fatalf("Error sending DELETE request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		log.Printf("Model %d stopped successfully", modelID)
-	} else {
-		log.Printf("Failed to stop model %d, status code: %d", modelID, resp.StatusCode)
-	}
+func stopModel(modelID int) error {
+	_, err := apiClient().StopInstance(context.Background(), modelID)
+	return clientError(err)
 }
 
 // 停止整个服务
-func stopServer() {
-	url := "http://localhost:9090/stop"
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte{}))
-	if err != nil {
-		log.Fatalf("%v", err)
-	}
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Fatalf("%v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusOK {
-		log.Println("Server stopped successfully")
-		os.Exit(0)
-	} else {
-		log.Printf("Failed to stop server, status code: %d", resp.StatusCode)
-	}
+func stopServer() error {
+	return clientError(apiClient().Shutdown(context.Background()))
 }
@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 模型进程的状态
+const (
+	statusStarting   = "starting"   // 已启动，等待 llama-server 就绪
+	statusRunning    = "running"    // 健康检查通过
+	statusRestarting = "restarting" // 意外退出，等待重启
+	statusFailed     = "failed"     // 重启次数用完
+	statusStopped    = "stopped"    // 已被停止，只出现在 model.stopped 事件中
+	statusRefused    = "refused"    // 未能启动，只出现在 model.refused 事件中
+)
+
+const (
+	// maxRestarts 模型进程意外退出后自动重启的最大次数
+	maxRestarts = 3
+	// restartBackoff 每次重启前等待的时间，按重启次数递增
+	restartBackoff = 2 * time.Second
+	// readyTimeout 等待 llama-server 就绪的最长时间，大模型加载可能很慢
+	readyTimeout = 10 * time.Minute
+	// statsInterval 从 /proc 采样实例资源占用的间隔，查询返回最近一次的结果
+	statsInterval = time.Second
+)
+
+// llamaServerPath llama-server 的安装位置
+const llamaServerPath = "/usr/local/oneinfer/llama/llama-server"
+
+// pid 返回模型进程当前的进程 ID，没有进程时返回 0
+func (mp *ModelProcess) pid() int {
+	if mp.Command != nil && mp.Command.Process != nil {
+		return mp.Command.Process.Pid
+	}
+	return 0
+}
+
+// lastInstanceID 最近分配的实例 ID，由 modelMux 保护
+var lastInstanceID int
+
+// allocInstanceID 分配新的实例 ID。ID 单调递增、不会复用：实例自动重启后进程 ID 会变化，
+// 原来的进程 ID 可能被系统分配给新启动的实例，因此不能用作实例 ID。
+// 第一次分配时跳过日志目录中已有的编号，避免覆盖之前的实例日志。调用方需持有 modelMux。
+func allocInstanceID() int {
+	if lastInstanceID == 0 {
+		if dir, err := instanceLogDir(); err == nil {
+			entries, _ := os.ReadDir(dir)
+			for _, entry := range entries {
+				if id, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".log")); err == nil && id > lastInstanceID {
+					lastInstanceID = id
+				}
+			}
+		}
+	}
+	lastInstanceID++
+	return lastInstanceID
+}
+
+// displayName 返回模型的注册表名称，没有时返回模型路径
+func (mp *ModelProcess) displayName() string {
+	if mp.Name != "" {
+		return mp.Name
+	}
+	return mp.Model
+}
+
+// dialAddr 返回从本机访问 llama-server 的地址，监听所有地址时使用回环地址
+func (mp *ModelProcess) dialAddr() string {
+	host := mp.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(mp.Port))
+}
+
+// status 返回可序列化的进程信息和资源占用。调用方需持有 modelMux。
+func (mp *ModelProcess) status() ModelProcessStatus {
+	s := ModelProcessStatus{
+		ID:       mp.ID,
+		Model:    mp.Model,
+		Name:     mp.Name,
+		Host:     mp.Host,
+		Port:     mp.Port,
+		Status:   mp.Status,
+		Restarts: mp.Restarts,
+	}
+	// 等待重启或已失败的实例没有进程
+	if mp.Status != statusStarting && mp.Status != statusRunning || mp.exited {
+		return s
+	}
+	s.UptimeSeconds = int64(time.Since(mp.started).Seconds())
+	// 资源占用由 sampleInstances 定期更新，新启动的进程在第一次采样前没有数据
+	if st := mp.stats; st != nil {
+		s.CPUPercent = mp.cpuPercent
+		s.RSSBytes = st.RSS
+		s.Threads = st.Threads
+		s.Connections = st.Connections
+	}
+	return s
+}
+
+// sampleInstances 每隔 statsInterval 采样一次所有实例的资源占用，在 serve 启动时运行
+func sampleInstances() {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		sampleInstanceStats()
+	}
+}
+
+// sampleInstanceStats 从 /proc 读取所有实例进程组的资源占用。
+// 扫描 /proc 较慢，只在收集进程组和写回结果时持有 modelMux，不阻塞启动、停止等操作。
+func sampleInstanceStats() {
+	modelMux.Lock()
+	groups := map[*ModelProcess]int{}
+	pgids := map[int]bool{}
+	for _, mp := range models {
+		if (mp.Status == statusStarting || mp.Status == statusRunning) && !mp.exited && mp.pid() != 0 {
+			groups[mp] = mp.pid()
+			pgids[mp.pid()] = true
+		}
+	}
+	modelMux.Unlock()
+	if len(groups) == 0 {
+		return
+	}
+
+	stats := readGroupStats(pgids)
+	now := time.Now()
+
+	modelMux.Lock()
+	defer modelMux.Unlock()
+	for mp, pgid := range groups {
+		// 采样期间进程已退出或已被重启
+		if mp.exited || mp.pid() != pgid {
+			continue
+		}
+		st, ok := stats[pgid]
+		if !ok {
+			continue
+		}
+		// CPU 占用率为两次采样之间的 CPU 时间除以经过的时间，100% 表示占满一个核
+		if elapsed := now.Sub(mp.sampledAt); elapsed > 0 {
+			mp.cpuPercent = math.Round((st.CPUSeconds-mp.cpuSample)/elapsed.Seconds()*1000) / 10
+		}
+		mp.cpuSample, mp.sampledAt = st.CPUSeconds, now
+		mp.stats = &st
+	}
+}
+
+// spawnModel 启动 llama-server 进程，输出追加到 logFile。调用方需持有 modelMux。
+func spawnModel(mp *ModelProcess, logFile *os.File) error {
+	cmd := exec.Command(llamaServerPath, mp.Args...)
+
+	// 分离进程，不让 serve 进程被阻塞
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	mp.Command = cmd
+	mp.exited = false
+	mp.Status = statusStarting
+	// 新进程从零开始计时，第一次查询的 CPU 占用率为启动以来的平均值
+	mp.started = time.Now()
+	mp.cpuSample, mp.sampledAt, mp.cpuPercent, mp.stats = 0, mp.started, 0, nil
+	go watchModel(mp, cmd, time.Now())
+	return nil
+}
+
+// watchModel 等待 llama-server 就绪并记录启动耗时；进程意外退出时按退避时间自动重启
+func watchModel(mp *ModelProcess, cmd *exec.Cmd, started time.Time) {
+	exited := make(chan struct{})
+	go waitReady(mp, cmd, started, exited)
+
+	// 进程退出后先标记，再回收。回收之前进程 ID 不会被复用，
+	// stopModelProcess 据此判断是否还能向进程组发送信号。
+	// 不支持 waitExited 的系统上只能在回收之后标记。
+	markExited := func() {
+		modelMux.Lock()
+		if mp.Command == cmd {
+			mp.exited = true
+		}
+		modelMux.Unlock()
+	}
+	err := waitExited(cmd.Process.Pid)
+	if err == nil {
+		markExited()
+	} else {
+		slog.Debug("cannot wait for the model process without reaping it", "id", mp.ID, "error", err)
+	}
+	cmd.Wait()
+	if err != nil {
+		markExited()
+	}
+	close(exited)
+
+	modelMux.Lock()
+	defer modelMux.Unlock()
+	// 已被停止或已被新进程替换
+	if mp.stopping || mp.Command != cmd || models[mp.ID] != mp {
+		return
+	}
+	logger := slog.With("id", mp.ID, "model", mp.displayName())
+	if mp.Restarts >= maxRestarts {
+		mp.Status = statusFailed
+		logger.Error("model process exited, giving up after restarts", "error", cmd.ProcessState.String(), "restarts", mp.Restarts)
+		publishCrash(mp, cmd)
+		publishModelEvent(eventModelFailed, mp, fmt.Sprintf("gave up after %d restarts", mp.Restarts), 0)
+		return
+	}
+
+	mp.Status = statusRestarting
+	mp.Restarts++
+	modelRestarts.inc(mp.displayName())
+	delay := time.Duration(mp.Restarts) * restartBackoff
+	logger.Warn("model process exited unexpectedly, restarting", "error", cmd.ProcessState.String(), "attempt", mp.Restarts, "delay", delay.String())
+	publishCrash(mp, cmd)
+
+	go func() {
+		time.Sleep(delay)
+		modelMux.Lock()
+		defer modelMux.Unlock()
+		if mp.stopping || models[mp.ID] != mp {
+			return
+		}
+		logFile, err := os.OpenFile(mp.LogPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			mp.Status = statusFailed
+			logger.Error("failed to open model log file", "error", err)
+			publishModelEvent(eventModelFailed, mp, err.Error(), 0)
+			return
+		}
+		defer logFile.Close()
+		fmt.Fprintf(logFile, "\n--- oneinfer: restarting model (attempt %d of %d) ---\n", mp.Restarts, maxRestarts)
+		if err := spawnModel(mp, logFile); err != nil {
+			mp.Status = statusFailed
+			logger.Error("failed to restart model", "error", err)
+			publishModelEvent(eventModelFailed, mp, err.Error(), 0)
+			return
+		}
+		publishModelEvent(eventModelRestarted, mp, "", 0)
+	}()
+}
+
+// waitReady 轮询 llama-server 的 /health，直到就绪、进程退出或超时
+func waitReady(mp *ModelProcess, cmd *exec.Cmd, started time.Time, exited <-chan struct{}) {
+	url := "http://" + mp.dialAddr() + "/health"
+	client := &http.Client{Timeout: 2 * time.Second}
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(readyTimeout)
+	for {
+		select {
+		case <-exited:
+			return
+		case <-deadline:
+			return
+		case <-ticker.C:
+		}
+		resp, err := client.Get(url)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		elapsed := time.Since(started)
+		modelStartDuration.observe(elapsed.Seconds(), mp.displayName())
+		modelMux.Lock()
+		if mp.Command == cmd && mp.Status == statusStarting {
+			mp.Status = statusRunning
+			slog.Info("model ready", "id", mp.ID, "model", mp.displayName(), "startup", elapsed.String())
+			publishModelEvent(eventModelReady, mp, "", elapsed)
+		}
+		modelMux.Unlock()
+		return
+	}
+}
+
+// publishCrash 发布 model.crashed 事件；进程被 SIGKILL 杀死而不是由 serve 停止时，
+// 多半是内核的 OOM killer，同时发布 model.oom_killed 事件
+func publishCrash(mp *ModelProcess, cmd *exec.Cmd) {
+	reason := cmd.ProcessState.String()
+	publishModelEvent(eventModelCrashed, mp, reason, 0)
+	if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && ws.Signaled() && ws.Signal() == syscall.SIGKILL {
+		slog.Warn("model process was killed by SIGKILL, probably out of memory", "id", mp.ID, "model", mp.displayName())
+		publishModelEvent(eventModelOOMKilled, mp, reason+" (probably killed by the kernel OOM killer)", 0)
+	}
+}
+
+// refuseModel 发布 model.refused 事件。调用方需持有 modelMux。
+func refuseModel(mp *ModelProcess, reason string) {
+	mp.Status = statusRefused
+	publishModelEvent(eventModelRefused, mp, reason, 0)
+}
+
+// stopModelProcess 停止模型进程组，进程已退出时不报错。调用方需持有 modelMux。
+// 已退出的进程（如等待重启的实例）不再发送信号，它的进程 ID 可能已被其他进程使用。
+func stopModelProcess(mp *ModelProcess) error {
+	mp.stopping = true
+	if mp.pid() == 0 || mp.exited {
+		return nil
+	}
+	if err := syscall.Kill(-mp.pid(), syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
+// instanceLogPath 返回以实例 ID 命名的日志文件路径
+func instanceLogPath(logFile *os.File, id int) string {
+	return filepath.Join(filepath.Dir(logFile.Name()), strconv.Itoa(id)+".log")
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestAllocInstanceID(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir, err := instanceLogDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(dir, 0755)
+	for _, name := range []string{"7.log", "41.log", "starting-123.log", "notes.txt"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+
+	saved := lastInstanceID
+	defer func() { lastInstanceID = saved }()
+	lastInstanceID = 0
+	if id := allocInstanceID(); id != 42 {
+		t.Errorf("first ID = %d, want 42 (after the existing logs)", id)
+	}
+	if id := allocInstanceID(); id != 43 {
+		t.Errorf("second ID = %d, want 43", id)
+	}
+}
+
+// startGroup 在新的进程组中启动命令
+func startGroup(t *testing.T, name string, args ...string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start %s: %v", name, err)
+	}
+	return cmd
+}
+
+func TestStopModelProcessSkipsExitedProcess(t *testing.T) {
+	cmd := startGroup(t, "sleep", "30")
+	defer cmd.Process.Kill()
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+
+	// 标记为已退出的进程不应再收到信号
+	mp := &ModelProcess{Command: cmd, exited: true}
+	if err := stopModelProcess(mp); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+		t.Fatal("stopModelProcess signalled a process marked as exited")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	mp.exited = false
+	if err := stopModelProcess(mp); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("process was not stopped")
+	}
+
+	// 没有进程时不能向 pid 0（即 serve 自己的进程组）发送信号
+	if err := stopModelProcess(&ModelProcess{}); err != nil {
+		t.Fatal(err)
+	}
+}
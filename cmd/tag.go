@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// 别名可以包含 :，如 qwen:7b
+	aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]{0,127}$`)
+	labelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+)
+
+// tagOutput tag/untag/label 命令的输出结构
+type tagOutput struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+	Labels  []string `json:"labels"`
+}
+
+// newTagOutput 从模型记录生成输出
+func newTagOutput(model modelInfo) tagOutput {
+	out := tagOutput{Name: model.Name, Aliases: model.Aliases, Labels: model.Labels}
+	if out.Aliases == nil {
+		out.Aliases = []string{}
+	}
+	if out.Labels == nil {
+		out.Labels = []string{}
+	}
+	return out
+}
+
+// printTagOutput 输出模型的别名和标签
+func printTagOutput(model modelInfo) error {
+	out := newTagOutput(model)
+	return printResult(out, func(w io.Writer) {
+		fmt.Fprintf(w, "Model '%s'\n  aliases: %s\n  labels:  %s\n", out.Name, strings.Join(out.Aliases, ", "), strings.Join(out.Labels, ", "))
+	})
+}
+
+var tagCmd = &cobra.Command{
+	Use:               "tag <model_name> <alias>",
+	Short:             "Add an alias that can be used instead of the model name",
+	Args:              validArgs(cobra.ExactArgs(2)),
+	ValidArgsFunction: completeModelNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		model, err := addAlias(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		return printTagOutput(model)
+	},
+}
+
+var untagCmd = &cobra.Command{
+	Use:               "untag <alias>",
+	Short:             "Remove a model alias",
+	Args:              validArgs(cobra.ExactArgs(1)),
+	ValidArgsFunction: completeAliases,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		model, err := removeAlias(args[0])
+		if err != nil {
+			return err
+		}
+		return printTagOutput(model)
+	},
+}
+
+var labelCmd = &cobra.Command{
+	Use:   "label <model_name> <label>...",
+	Short: "Add or remove free-form labels such as chat, embedding or prod",
+	Args:  validArgs(cobra.MinimumNArgs(2)),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeModelNames(cmd, args, toComplete)
+		}
+		return completeLabels(cmd, nil, toComplete)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		remove, _ := cmd.Flags().GetBool("remove")
+		model, err := setLabels(args[0], args[1:], remove)
+		if err != nil {
+			return err
+		}
+		return printTagOutput(model)
+	},
+}
+
+func init() {
+	labelCmd.Flags().Bool("remove", false, "Remove the labels instead of adding them")
+	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(untagCmd)
+	rootCmd.AddCommand(labelCmd)
+}
+
+// addAlias 为模型添加别名，别名不能与任何模型名或别名重复
+func addAlias(name, alias string) (modelInfo, error) {
+	if !aliasPattern.MatchString(alias) {
+		return modelInfo{}, newError(errValidation, "invalid alias %q: use letters, digits, '.', '_', ':' or '-'", alias)
+	}
+	return updateModel(name, func(models []modelInfo, model *modelInfo) error {
+		for _, m := range models {
+			if m.matches(alias) {
+				return newError(errConflict, "alias '%s' is already used by model '%s'", alias, m.Name)
+			}
+		}
+		model.Aliases = append(model.Aliases, alias)
+		sort.Strings(model.Aliases)
+		return nil
+	})
+}
+
+// removeAlias 删除别名
+func removeAlias(alias string) (modelInfo, error) {
+	model, err := findModel(alias)
+	if err != nil {
+		return modelInfo{}, err
+	}
+	if model.Name == alias {
+		return modelInfo{}, newError(errValidation, "'%s' is a model name, not an alias; use 'oneinfer rm' to remove the model", alias)
+	}
+	return updateModel(alias, func(models []modelInfo, model *modelInfo) error {
+		model.Aliases = removeString(model.Aliases, alias)
+		return nil
+	})
+}
+
+// setLabels 为模型添加或删除标签
+func setLabels(name string, labels []string, remove bool) (modelInfo, error) {
+	for _, label := range labels {
+		if !labelPattern.MatchString(label) {
+			return modelInfo{}, newError(errValidation, "invalid label %q: use letters, digits, '.', '_' or '-'", label)
+		}
+	}
+	return updateModel(name, func(models []modelInfo, model *modelInfo) error {
+		for _, label := range labels {
+			model.Labels = removeString(model.Labels, label)
+			if !remove {
+				model.Labels = append(model.Labels, label)
+			}
+		}
+		sort.Strings(model.Labels)
+		return nil
+	})
+}
+
+// removeString 返回删除 s 之后的列表
+func removeString(list []string, s string) []string {
+	out := list[:0]
+	for _, item := range list {
+		if item != s {
+			out = append(out, item)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// completeAliases 补全已有的别名
+func completeAliases(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	models, err := listModels()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var aliases []string
+	for _, model := range models {
+		for _, alias := range model.Aliases {
+			if strings.HasPrefix(alias, toComplete) {
+				aliases = append(aliases, alias+"\talias of "+model.Name)
+			}
+		}
+	}
+	return aliases, cobra.ShellCompDirectiveNoFileComp
+}
+
+// containsString 判断列表中是否包含 s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// clearScreen 把光标移到左上角并清屏
+const clearScreen = "\033[H\033[2J"
+
+// top 命令
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show live resource usage of running models",
+	Long: `Show the status and resource usage of running models, refreshed every --interval until
+interrupted. The columns are the same as in 'oneinfer ps'.
+
+With --output json or yaml, or when stdout is not a terminal, each refresh is printed
+after the previous one instead of redrawing the screen.`,
+	Args: validArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		iterations, _ := cmd.Flags().GetInt("iterations")
+		if interval < time.Second/10 {
+			return newError(errValidation, "--interval must be at least 100ms")
+		}
+		if iterations < 0 {
+			return newError(errValidation, "--iterations must not be negative")
+		}
+
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		defer signal.Stop(interrupted)
+
+		redraw := !machineOutput() && isTerminal(os.Stdout)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for i := 1; ; i++ {
+			if err := printTop(redraw, interval); err != nil {
+				return err
+			}
+			if iterations > 0 && i >= iterations {
+				return nil
+			}
+			select {
+			case <-interrupted:
+				return nil
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+func init() {
+	topCmd.Flags().DurationP("interval", "i", 2*time.Second, "Refresh interval")
+	topCmd.Flags().IntP("iterations", "n", 0, "Exit after this many refreshes (0 runs until interrupted)")
+	rootCmd.AddCommand(topCmd)
+}
+
+// printTop 获取一次实例列表并输出
+func printTop(redraw bool, interval time.Duration) error {
+	models, err := listRunningModels()
+	if err != nil {
+		return err
+	}
+	return printResult(instanceListOutput{Instances: models}, func(w io.Writer) {
+		if redraw {
+			io.WriteString(w, clearScreen)
+		}
+		fmt.Fprintf(w, "oneinfer top - %s, %d instance(s), refresh every %s\n\n",
+			time.Now().Format("15:04:05"), len(models), interval)
+		if len(models) == 0 {
+			io.WriteString(w, "No running models found.\n")
+			return
+		}
+		writeInstanceTable(w, models)
+		if !redraw {
+			io.WriteString(w, "\n")
+		}
+	})
+}
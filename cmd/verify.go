@@ -0,0 +1,402 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// 文件校验结果
+const (
+	checkOK           = "ok"
+	checkMissing      = "missing"
+	checkSizeMismatch = "size_mismatch"
+	checkHashMismatch = "hash_mismatch"
+	checkCorrupt      = "corrupt"
+	checkMissingLink  = "missing_link"
+)
+
+// fileCheck 单个文件的校验结果
+type fileCheck struct {
+	Path     string `json:"path"`
+	Location string `json:"location"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Repaired bool   `json:"repaired,omitempty"`
+}
+
+// modelCheck 单个模型的校验结果
+type modelCheck struct {
+	Name     string      `json:"name"`
+	Status   string      `json:"status"` // ok、failed、repaired 或 unverified（旧版本注册，没有校验和）
+	Files    []fileCheck `json:"files"`
+	Warnings []string    `json:"warnings,omitempty"`
+}
+
+// verifyOutput verify 命令的输出结构
+type verifyOutput struct {
+	Models []modelCheck `json:"models"`
+	Failed int          `json:"failed"`
+}
+
+var verifyCmd = &cobra.Command{
+	Use:               "verify [model_name]",
+	Short:             "Check the integrity of model files on disk",
+	Long:              "Re-hash model files against the checksums recorded when they were added, check that GGUF headers and tensor data fit within each file, and report files that are missing. With --repair, broken files are restored from the original local path or re-downloaded from the recorded platform.",
+	Args:              validArgs(cobra.MaximumNArgs(1)),
+	ValidArgsFunction: completeModelNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repair, _ := cmd.Flags().GetBool("repair")
+		noHash, _ := cmd.Flags().GetBool("no-hash")
+		installDeps, _ := cmd.Flags().GetBool("install-deps")
+
+		var models []modelInfo
+		if len(args) == 1 {
+			model, err := findModel(args[0])
+			if err != nil {
+				return err
+			}
+			models = []modelInfo{model}
+		} else {
+			var err error
+			if models, err = listModels(); err != nil {
+				return err
+			}
+		}
+
+		out := verifyOutput{Models: []modelCheck{}}
+		for _, model := range models {
+			fmt.Fprintf(infoOut(), "Verifying %s...\n", model.Name)
+			check := verifyModel(model, !noHash)
+			if check.Status == "failed" && repair {
+				check = repairModel(model, check, !noHash, installDeps)
+			}
+			if check.Status == "failed" {
+				out.Failed++
+			}
+			out.Models = append(out.Models, check)
+		}
+
+		if err := printResult(out, func(w io.Writer) {
+			t := &table{headers: []string{"MODEL", "STATUS", "ISSUES"}, wideHeaders: []string{"DETAILS"}}
+			for _, check := range out.Models {
+				var issues, details []string
+				for _, f := range check.Files {
+					if f.Status != checkOK || f.Repaired {
+						issues = append(issues, f.Path+": "+f.Status)
+						if f.Detail != "" {
+							details = append(details, f.Path+": "+f.Detail)
+						}
+					}
+				}
+				details = append(details, check.Warnings...)
+				t.addRow([]string{check.Name, check.Status, strings.Join(issues, ", ")}, strings.Join(details, "; "))
+			}
+			t.write(w)
+		}); err != nil {
+			return err
+		}
+
+		if out.Failed > 0 {
+			return newError(errGeneric, "%d model(s) failed verification", out.Failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	verifyCmd.Flags().Bool("repair", false, "Restore broken or missing files from the original local path or by re-downloading them")
+	verifyCmd.Flags().Bool("no-hash", false, "Skip re-hashing and only check file sizes and GGUF structure")
+	verifyCmd.Flags().Bool("install-deps", false, "Install missing Python download libraries with pip when repairing")
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// verifyModel 校验模型的所有文件
+func verifyModel(model modelInfo, hash bool) modelCheck {
+	check := modelCheck{Name: model.Name, Status: "ok", Files: []fileCheck{}}
+
+	// 旧版本注册的模型没有清单，只能检查文件是否存在以及 GGUF 结构
+	if model.Files == nil {
+		fc := fileCheck{Path: filepath.Base(model.Path), Location: model.Path, Status: checkOK}
+		if info, err := os.Stat(model.Path); err != nil {
+			fc.Status, fc.Detail = checkMissing, err.Error()
+		} else if info.Mode().IsRegular() {
+			fc.Status, fc.Detail, check.Warnings = checkGGUFStructure(model.Path, check.Warnings)
+		}
+		check.Files = append(check.Files, fc)
+		if fc.Status != checkOK {
+			check.Status = "failed"
+		} else {
+			check.Status = "unverified"
+			check.Warnings = append(check.Warnings, "no checksums recorded (added by an older version of oneinfer)")
+		}
+		return check
+	}
+
+	view := ""
+	if model.Mode != importInPlace {
+		if root, err := modelsDir(); err == nil {
+			view = filepath.Join(root, viewDirName(model.Name))
+		}
+	}
+
+	for _, f := range model.Files {
+		fc := verifyFile(f, hash)
+		if fc.Status == checkOK && view != "" {
+			// 视图目录中的链接需要指向实际文件
+			if _, err := os.Stat(filepath.Join(view, f.Path)); err != nil {
+				fc.Status, fc.Detail = checkMissingLink, "model directory link is missing or broken"
+			}
+		}
+		var warnings []string
+		if fc.Status == checkOK {
+			fc.Status, fc.Detail, warnings = checkGGUFStructure(fc.Location, nil)
+		}
+		for _, w := range warnings {
+			check.Warnings = append(check.Warnings, f.Path+": "+w)
+		}
+		if fc.Status != checkOK {
+			check.Status = "failed"
+		}
+		check.Files = append(check.Files, fc)
+	}
+	return check
+}
+
+// verifyFile 检查文件是否存在、大小和摘要是否与清单一致
+func verifyFile(f modelFile, hash bool) fileCheck {
+	location, err := f.filePath()
+	fc := fileCheck{Path: f.Path, Location: location, Status: checkOK}
+	if err != nil {
+		fc.Status, fc.Detail = checkCorrupt, err.Error()
+		return fc
+	}
+
+	info, err := os.Stat(location)
+	if err != nil {
+		fc.Status, fc.Detail = checkMissing, err.Error()
+		return fc
+	}
+	if info.Size() != f.Size {
+		fc.Status, fc.Detail = checkSizeMismatch, fmt.Sprintf("expected %d bytes, found %d", f.Size, info.Size())
+		return fc
+	}
+	if hash {
+		digest, _, err := hashFile(location)
+		if err != nil {
+			fc.Status, fc.Detail = checkMissing, err.Error()
+			return fc
+		}
+		if digest != f.Digest {
+			fc.Status, fc.Detail = checkHashMismatch, fmt.Sprintf("expected %s, found %s", f.Digest, digest)
+		}
+	}
+	return fc
+}
+
+// checkGGUFStructure 对 GGUF 文件检查文件头以及张量数据是否超出文件大小，其他文件直接通过
+func checkGGUFStructure(path string, warnings []string) (string, string, []string) {
+	if !isGGUF(path) {
+		if strings.HasSuffix(strings.ToLower(path), ".gguf") {
+			return checkCorrupt, "missing GGUF header", warnings
+		}
+		return checkOK, "", warnings
+	}
+	gguf, err := readGGUF(path)
+	if err != nil {
+		return checkCorrupt, err.Error(), warnings
+	}
+	issues, ws := gguf.checkTensorBounds()
+	warnings = append(warnings, ws...)
+	if len(issues) > 0 {
+		return checkCorrupt, strings.Join(issues, "; "), warnings
+	}
+	return checkOK, "", warnings
+}
+
+// repairModel 恢复校验失败的文件，然后重新校验
+func repairModel(model modelInfo, check modelCheck, hash bool, installDeps bool) modelCheck {
+	if model.Files == nil {
+		check.Warnings = append(check.Warnings, "cannot repair: no checksums recorded")
+		return check
+	}
+
+	// 需要恢复内容的文件（按摘要）以及只需要重建链接的文件
+	needed := map[string]bool{}
+	broken := map[string]bool{}
+	for i, fc := range check.Files {
+		if fc.Status == checkOK {
+			continue
+		}
+		broken[fc.Path] = true
+		f := model.Files[i]
+		if fc.Status == checkMissingLink {
+			continue
+		}
+		if f.External != "" {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("cannot repair %s: it was imported with --mode %s and lives outside the blob store", f.Path, model.Mode))
+			continue
+		}
+		// 损坏的 blob 保留到找到校验通过的新内容后再替换
+		needed[f.Digest] = true
+	}
+
+	if len(needed) > 0 {
+		var err error
+		if isSupportedPlatform(model.Platform) {
+			err = restoreFromHub(model, needed, installDeps)
+		} else {
+			err = restoreFromLocal(model, needed)
+		}
+		if err != nil {
+			check.Warnings = append(check.Warnings, "repair failed: "+err.Error())
+		}
+	}
+
+	// 重建视图目录中的链接
+	if model.Mode != importInPlace {
+		if root, err := modelsDir(); err == nil {
+			view := filepath.Join(root, viewDirName(model.Name))
+			for _, f := range model.Files {
+				if !broken[f.Path] {
+					continue
+				}
+				target, err := f.filePath()
+				if err != nil {
+					continue
+				}
+				link := filepath.Join(view, f.Path)
+				os.Remove(link)
+				os.MkdirAll(filepath.Dir(link), 0755)
+				os.Symlink(target, link)
+			}
+		}
+	}
+
+	repaired := verifyModel(model, hash)
+	repaired.Warnings = append(check.Warnings, repaired.Warnings...)
+	for i := range repaired.Files {
+		if broken[repaired.Files[i].Path] && repaired.Files[i].Status == checkOK {
+			repaired.Files[i].Repaired = true
+		}
+	}
+	if repaired.Status == "ok" {
+		repaired.Status = "repaired"
+	}
+	return repaired
+}
+
+// restoreFromHub 重新下载模型，把摘要匹配的文件存回 blob 仓库
+func restoreFromHub(model modelInfo, needed map[string]bool, installDeps bool) error {
+	staging, err := createStagingDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	fmt.Fprintf(infoOut(), "Re-downloading %s from %s...\n", model.Source, model.Platform)
+	revision := downloadRevision(model.Platform, model.Revision, model.Commit)
+	if err := downloadModelWithPython(model.Platform, model.Source, revision, staging, model.FilePattern, installDeps); err != nil {
+		return err
+	}
+	files, err := collectModelFiles(staging)
+	if err != nil {
+		return err
+	}
+	return restoreBlobs(staging, files, needed, blobMove)
+}
+
+// restoreFromLocal 从添加模型时的本地原始路径恢复文件
+func restoreFromLocal(model modelInfo, needed map[string]bool) error {
+	if model.Source == "" {
+		return fmt.Errorf("model '%s' has no original path to restore from, import it again", model.Name)
+	}
+	info, err := os.Stat(model.Source)
+	if err != nil {
+		return fmt.Errorf("original path %s is no longer available", model.Source)
+	}
+	root := model.Source
+	if !info.IsDir() {
+		root = filepath.Dir(model.Source)
+	}
+	var files []string
+	for _, f := range model.Files {
+		if needed[f.Digest] {
+			files = append(files, filepath.FromSlash(f.Path))
+		}
+	}
+	return restoreBlobs(root, files, needed, importCopy)
+}
+
+// restoreBlobs 用 root 下摘要在 needed 中的文件替换对应的 blob，摘要不符的文件不会写入仓库
+func restoreBlobs(root string, files []string, needed map[string]bool, mode string) error {
+	for _, rel := range files {
+		src := filepath.Join(root, rel)
+		digest, _, err := hashFile(src)
+		if err != nil || !needed[digest] {
+			continue
+		}
+		if err := replaceBlob(src, digest, mode); err != nil {
+			return err
+		}
+		delete(needed, digest)
+	}
+	if len(needed) > 0 {
+		return fmt.Errorf("%d file(s) could not be found with the recorded checksum", len(needed))
+	}
+	return nil
+}
+
+// replaceBlob 用摘要为 digest 的文件原子地替换 blob（可能已损坏或缺失）。
+// blobMove 时直接重命名暂存文件，否则先复制到 blob 仓库中的临时文件并再次校验摘要，再覆盖原 blob。
+func replaceBlob(src, digest, mode string) error {
+	dir, err := blobsDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	blob, err := blobPath(digest)
+	if err != nil {
+		return err
+	}
+	if mode == blobMove && os.Rename(src, blob) == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(dir, "blob-*.partial")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), in)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %v", src, err)
+	}
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != digest {
+		return fmt.Errorf("%s changed while it was copied: expected %s, got %s", src, digest, got)
+	}
+	return os.Rename(tmp.Name(), blob)
+}
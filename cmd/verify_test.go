@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// importCorrupted 以 copy 方式导入一个 GGUF 文件，然后改写其 blob 的最后一个字节
+func importCorrupted(t *testing.T) (modelInfo, string, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	src := filepath.Join(home, "m.gguf")
+	data := validGGUF()
+	os.WriteFile(src, data, 0644)
+	model, err := importLocalModel("m", src, importCopy, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, _ := model.Files[0].filePath()
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(blob, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return model, src, blob
+}
+
+func TestRepairModelFromLocal(t *testing.T) {
+	model, _, _ := importCorrupted(t)
+	check := verifyModel(model, true)
+	if check.Status != "failed" || check.Files[0].Status != checkHashMismatch {
+		t.Fatalf("corrupted blob not detected: %+v", check)
+	}
+	repaired := repairModel(model, check, true, false)
+	if repaired.Status != "repaired" || !repaired.Files[0].Repaired {
+		t.Errorf("repair failed: %+v", repaired)
+	}
+}
+
+// 找不到校验通过的新内容时，损坏的 blob 保持原样，不会变成缺失
+func TestRepairModelKeepsBlobOnFailure(t *testing.T) {
+	model, src, blob := importCorrupted(t)
+	os.WriteFile(src, []byte("GGUF something else"), 0644)
+
+	check := verifyModel(model, true)
+	repaired := repairModel(model, check, true, false)
+	if repaired.Status != "failed" || len(repaired.Warnings) == 0 {
+		t.Errorf("repair from a changed source succeeded: %+v", repaired)
+	}
+	if repaired.Files[0].Status != checkHashMismatch {
+		t.Errorf("file status after a failed repair = %s, want %s", repaired.Files[0].Status, checkHashMismatch)
+	}
+	if _, err := os.Stat(blob); err != nil {
+		t.Errorf("blob was removed by a failed repair: %v", err)
+	}
+}
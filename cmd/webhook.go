@@ -0,0 +1,356 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// webhookAttempts 每个事件最多投递的次数
+	webhookAttempts = 5
+	// webhookTimeout 单次投递的超时时间
+	webhookTimeout = 10 * time.Second
+	// webhookSignatureHeader HMAC-SHA256 签名所在的请求头，值为 sha256=<hex>
+	webhookSignatureHeader = "X-OneInfer-Signature"
+	// eventWebhookTest webhook test 发送的事件类型
+	eventWebhookTest = "webhook.test"
+)
+
+// webhookBackoff 第一次重试前等待的时间，之后每次翻倍
+var webhookBackoff = 2 * time.Second
+
+// defaultWebhookEvents 未指定 --event 时订阅的事件：需要有人处理的故障
+var defaultWebhookEvents = []string{eventModelCrashed, eventModelOOMKilled, eventModelFailed, eventModelRefused}
+
+// webhookConfig 一个 webhook，保存在 ~/.oneinfer/config.json 中
+type webhookConfig struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`           // 事件类型或分组，如 model.crashed、model
+	Secret string   `json:"secret,omitempty"` // 用于 HMAC 签名
+}
+
+// webhookPayload 发送给 webhook 的 JSON
+type webhookPayload struct {
+	serverEvent
+	Host string `json:"host"` // 运行 serve 的主机名，便于区分多台机器
+}
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "List the webhooks called on model failures and other events",
+	Long: `List the webhooks that 'oneinfer serve' calls with a JSON payload when events happen.
+
+By default a webhook receives the failure events: ` + strings.Join(defaultWebhookEvents, ", ") + `.
+Failed deliveries are retried with backoff. When a webhook has a secret, each request carries an
+` + webhookSignatureHeader + ` header with sha256=<hex HMAC-SHA256 of the body>.`,
+	Args: validArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		hooks := cfg.masked().Webhooks
+		if hooks == nil {
+			hooks = []webhookConfig{}
+		}
+		return printResult(hooks, func(w io.Writer) {
+			if len(hooks) == 0 {
+				io.WriteString(w, "No webhooks configured.\n")
+				return
+			}
+			t := &table{headers: []string{"ID", "URL", "EVENTS", "SIGNED"}}
+			for _, hook := range hooks {
+				signed := "no"
+				if hook.Secret != "" {
+					signed = "yes"
+				}
+				t.addRow([]string{hook.ID, hook.URL, strings.Join(hook.Events, ","), signed})
+			}
+			t.write(w)
+		})
+	},
+}
+
+var webhookAddCmd = &cobra.Command{
+	Use:   "add <url>",
+	Short: "Add a webhook",
+	Args:  validArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		events, _ := cmd.Flags().GetStringSlice("event")
+		secret, _ := cmd.Flags().GetString("secret")
+		generate, _ := cmd.Flags().GetBool("generate-secret")
+
+		if u, err := url.Parse(args[0]); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return newError(errValidation, "invalid webhook URL %q: expected an http or https URL", args[0])
+		}
+		if secret != "" && generate {
+			return newError(errValidation, "--secret and --generate-secret cannot be used together")
+		}
+		if generate {
+			secret = newRequestID() + newRequestID()
+		}
+		if len(events) == 0 {
+			events = defaultWebhookEvents
+		}
+
+		hook := webhookConfig{ID: newRequestID()[:8], URL: args[0], Events: events, Secret: secret}
+		err := modifyConfig(func(cfg *oneinferConfig) error {
+			cfg.Webhooks = append(cfg.Webhooks, hook)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// 生成的密钥只显示这一次
+		out := hook
+		if !generate {
+			out.Secret = ""
+		}
+		return printResult(out, func(w io.Writer) {
+			fmt.Fprintf(w, "Webhook %s added for %s.\n", hook.ID, strings.Join(events, ", "))
+			if generate {
+				fmt.Fprintf(w, "Secret: %s\n", secret)
+			}
+		})
+	},
+}
+
+var webhookRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Remove a webhook",
+	Args:  validArgs(cobra.ExactArgs(1)),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return webhookIDs(), cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := modifyConfig(func(cfg *oneinferConfig) error {
+			if _, err := findWebhook(*cfg, args[0]); err != nil {
+				return err
+			}
+			kept := cfg.Webhooks[:0]
+			for _, hook := range cfg.Webhooks {
+				if hook.ID != args[0] {
+					kept = append(kept, hook)
+				}
+			}
+			cfg.Webhooks = kept
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return printResult(map[string]string{"id": args[0], "status": "removed"}, func(w io.Writer) {
+			fmt.Fprintf(w, "Webhook %s removed.\n", args[0])
+		})
+	},
+}
+
+var webhookTestCmd = &cobra.Command{
+	Use:   "test <id>",
+	Short: "Send a test event to a webhook",
+	Args:  validArgs(cobra.ExactArgs(1)),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return webhookIDs(), cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		hook, err := findWebhook(cfg, args[0])
+		if err != nil {
+			return err
+		}
+		data, _ := json.Marshal(map[string]string{"message": "test event from oneinfer"})
+		ev := serverEvent{Type: eventWebhookTest, Time: time.Now().UTC(), Data: data}
+		status, _, err := sendWebhook(hook, ev)
+		if err != nil {
+			return wrapError(errUnreachable, err, "webhook %s failed", hook.ID)
+		}
+		return printResult(map[string]interface{}{"id": hook.ID, "status": status}, func(w io.Writer) {
+			fmt.Fprintf(w, "Webhook %s responded with status %d.\n", hook.ID, status)
+		})
+	},
+}
+
+func init() {
+	webhookAddCmd.Flags().StringSlice("event", nil, "Event types or groups to send (default "+strings.Join(defaultWebhookEvents, ",")+")")
+	webhookAddCmd.Flags().String("secret", "", "Secret used to sign requests with HMAC-SHA256")
+	webhookAddCmd.Flags().Bool("generate-secret", false, "Generate a random secret and print it once")
+	webhookCmd.AddCommand(webhookAddCmd)
+	webhookCmd.AddCommand(webhookRemoveCmd)
+	webhookCmd.AddCommand(webhookTestCmd)
+	rootCmd.AddCommand(webhookCmd)
+}
+
+// findWebhook 按 ID 查找 webhook
+func findWebhook(cfg oneinferConfig, id string) (webhookConfig, error) {
+	for _, hook := range cfg.Webhooks {
+		if hook.ID == id {
+			return hook, nil
+		}
+	}
+	return webhookConfig{}, newError(errNotFound, "webhook %q not found", id)
+}
+
+// webhookIDs 返回所有 webhook 的 ID，用于补全
+func webhookIDs() []string {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, hook := range cfg.Webhooks {
+		ids = append(ids, hook.ID)
+	}
+	return ids
+}
+
+// signWebhook 返回 body 的 HMAC-SHA256 签名
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookCache serve 缓存的 webhook 配置，config.json 被替换或修改后重新读取
+var webhookCache struct {
+	sync.Mutex
+	loaded bool
+	info   os.FileInfo // 读取时的 config.json，文件不存在时为 nil
+	hooks  []webhookConfig
+}
+
+// loadWebhooks 返回配置的 webhook。每个事件都会调用，只有 config.json 变化时才重新读取和解析，
+// webhook add/remove 无需重启 serve。
+func loadWebhooks() ([]webhookConfig, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	webhookCache.Lock()
+	defer webhookCache.Unlock()
+	if webhookCache.loaded && sameFileVersion(info, webhookCache.info) {
+		return webhookCache.hooks, nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	webhookCache.loaded, webhookCache.info, webhookCache.hooks = true, info, cfg.Webhooks
+	return cfg.Webhooks, nil
+}
+
+// sameFileVersion 判断两次 Stat 的结果是否为同一个文件的同一个版本。
+// saveConfig 通过重命名替换文件，即使修改时间的精度不够也能通过 inode 发现变化。
+func sameFileVersion(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return os.SameFile(a, b) && a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
+
+// runWebhooks 订阅 serve 的事件并投递给匹配的 webhook
+func runWebhooks() {
+	var last uint64
+	for {
+		ch, replay := eventHub.subscribe(last)
+		for _, ev := range replay {
+			dispatchWebhooks(ev)
+			last = ev.ID
+		}
+		// 通道被关闭说明处理太慢，带着最后的事件 ID 重新订阅
+		for ev := range ch {
+			dispatchWebhooks(ev)
+			last = ev.ID
+		}
+	}
+}
+
+// dispatchWebhooks 把事件交给订阅了它的 webhook，每个投递在单独的 goroutine 中重试
+func dispatchWebhooks(ev serverEvent) {
+	hooks, err := loadWebhooks()
+	if err != nil {
+		slog.Error("cannot load webhooks", "error", err)
+		return
+	}
+	for _, hook := range hooks {
+		if matchEventTypes(ev.Type, hook.Events) {
+			go deliverWebhook(hook, ev)
+		}
+	}
+}
+
+// deliverWebhook 投递一个事件，网络错误、429 和 5xx 按退避时间重试
+func deliverWebhook(hook webhookConfig, ev serverEvent) {
+	logger := slog.With("webhook", hook.ID, "event", ev.Type, "event_id", ev.ID)
+	delay := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		status, retry, err := sendWebhook(hook, ev)
+		if err == nil {
+			logger.Info("webhook delivered", "status", status, "attempt", attempt)
+			return
+		}
+		if !retry || attempt == webhookAttempts {
+			logger.Error("webhook delivery failed", "error", err, "attempt", attempt)
+			return
+		}
+		logger.Warn("webhook delivery failed, retrying", "error", err, "attempt", attempt, "delay", delay.String())
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// sendWebhook 发送一次请求，返回状态码以及失败时是否值得重试
+func sendWebhook(hook webhookConfig, ev serverEvent) (int, bool, error) {
+	host, _ := os.Hostname()
+	body, err := json.Marshal(webhookPayload{serverEvent: ev, Host: host})
+	if err != nil {
+		return 0, false, err
+	}
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "oneinfer-webhook")
+	req.Header.Set("X-OneInfer-Event", ev.Type)
+	req.Header.Set("X-OneInfer-Delivery", fmt.Sprintf("%d", ev.ID))
+	if hook.Secret != "" {
+		req.Header.Set(webhookSignatureHeader, signWebhook(hook.Secret, body))
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return resp.StatusCode, retry, fmt.Errorf("server returned status %d", resp.StatusCode)
+}
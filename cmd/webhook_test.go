@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver 记录收到的请求，并依次返回 statuses 中的状态码，用完后返回 200
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedWebhook
+	received chan struct{}
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) (*webhookReceiver, string) {
+	r := &webhookReceiver{statuses: statuses, received: make(chan struct{}, 16)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, receivedWebhook{header: req.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		r.mu.Unlock()
+		w.WriteHeader(status)
+		r.received <- struct{}{}
+	}))
+	t.Cleanup(srv.Close)
+	return r, srv.URL
+}
+
+func (r *webhookReceiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// wait 等待第 n 个请求到达
+func (r *webhookReceiver) wait(t *testing.T, n int) {
+	t.Helper()
+	for r.count() < n {
+		select {
+		case <-r.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d webhook requests, want %d", r.count(), n)
+		}
+	}
+}
+
+// fastWebhookBackoff 缩短测试中的重试间隔
+func fastWebhookBackoff(t *testing.T) {
+	saved := webhookBackoff
+	webhookBackoff = time.Millisecond
+	t.Cleanup(func() { webhookBackoff = saved })
+}
+
+func testEvent(eventType string) serverEvent {
+	return serverEvent{ID: 7, Type: eventType, Time: time.Now().UTC(), Data: json.RawMessage(`{"model":"qwen3"}`)}
+}
+
+func TestSendWebhookSignature(t *testing.T) {
+	recv, url := newWebhookReceiver(t)
+	hook := webhookConfig{ID: "h1", URL: url, Secret: "s3cret"}
+	status, _, err := sendWebhook(hook, testEvent(eventModelCrashed))
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("sendWebhook = %d, %v", status, err)
+	}
+
+	req := recv.requests[0]
+	if got, want := req.header.Get(webhookSignatureHeader), signWebhook("s3cret", req.body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := signWebhook("other", req.body); got == req.header.Get(webhookSignatureHeader) {
+		t.Error("signature does not depend on the secret")
+	}
+	if req.header.Get("X-OneInfer-Event") != eventModelCrashed || req.header.Get("X-OneInfer-Delivery") != "7" {
+		t.Errorf("unexpected headers: %v", req.header)
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Type != eventModelCrashed || string(payload.Data) != `{"model":"qwen3"}` {
+		t.Errorf("unexpected payload: %s", req.body)
+	}
+
+	// 没有密钥时不签名
+	sendWebhook(webhookConfig{ID: "h2", URL: url}, testEvent(eventModelCrashed))
+	if sig := recv.requests[1].header.Get(webhookSignatureHeader); sig != "" {
+		t.Errorf("unsigned webhook sent signature %q", sig)
+	}
+}
+
+func TestDeliverWebhookRetries(t *testing.T) {
+	fastWebhookBackoff(t)
+
+	recv, url := newWebhookReceiver(t, 500, 503, 429)
+	start := time.Now()
+	deliverWebhook(webhookConfig{ID: "h1", URL: url}, testEvent(eventModelCrashed))
+	if n := recv.count(); n != 4 {
+		t.Errorf("5xx/429: %d attempts, want 4", n)
+	}
+	// 退避时间逐次翻倍：1ms + 2ms + 4ms
+	if elapsed := time.Since(start); elapsed < 7*time.Millisecond {
+		t.Errorf("retries took %v, want at least 7ms of backoff", elapsed)
+	}
+
+	recv, url = newWebhookReceiver(t, 500, 500, 500, 500, 500, 500)
+	deliverWebhook(webhookConfig{ID: "h2", URL: url}, testEvent(eventModelCrashed))
+	if n := recv.count(); n != webhookAttempts {
+		t.Errorf("persistent 5xx: %d attempts, want %d", n, webhookAttempts)
+	}
+}
+
+func TestDeliverWebhookNoRetryOn4xx(t *testing.T) {
+	fastWebhookBackoff(t)
+	for _, status := range []int{400, 401, 404, 410} {
+		recv, url := newWebhookReceiver(t, status)
+		deliverWebhook(webhookConfig{ID: "h1", URL: url}, testEvent(eventModelCrashed))
+		if n := recv.count(); n != 1 {
+			t.Errorf("status %d: %d attempts, want 1", status, n)
+		}
+	}
+}
+
+func TestDispatchWebhooksFiltersEvents(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	crashed, crashedURL := newWebhookReceiver(t)
+	all, allURL := newWebhookReceiver(t)
+	cfg := oneinferConfig{Webhooks: []webhookConfig{
+		{ID: "crashed", URL: crashedURL, Events: []string{eventModelCrashed}},
+		{ID: "downloads", URL: allURL, Events: []string{"download"}},
+	}}
+	if err := saveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatchWebhooks(testEvent("download.progress"))
+	dispatchWebhooks(testEvent(eventModelCrashed))
+	dispatchWebhooks(testEvent(eventModelOOMKilled))
+	crashed.wait(t, 1)
+	all.wait(t, 1)
+	time.Sleep(100 * time.Millisecond)
+	if n := crashed.count(); n != 1 {
+		t.Errorf("model.crashed webhook received %d events, want 1", n)
+	}
+	if got := crashed.requests[0].header.Get("X-OneInfer-Event"); got != eventModelCrashed {
+		t.Errorf("model.crashed webhook received %s", got)
+	}
+	if n := all.count(); n != 1 {
+		t.Errorf("download webhook received %d events, want 1", n)
+	}
+	if got := all.requests[0].header.Get("X-OneInfer-Event"); got != "download.progress" {
+		t.Errorf("download webhook received %s", got)
+	}
+}
+
+// config.json 只在变化后重新读取
+func TestLoadWebhooksCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	hooks, err := loadWebhooks()
+	if err != nil || len(hooks) != 0 {
+		t.Fatalf("no config: %v, %v", hooks, err)
+	}
+
+	saveConfig(oneinferConfig{Webhooks: []webhookConfig{{ID: "a", URL: "http://127.0.0.1:1"}}})
+	hooks, _ = loadWebhooks()
+	if len(hooks) != 1 || hooks[0].ID != "a" {
+		t.Fatalf("after add: %v", hooks)
+	}
+
+	// 缓存命中时不读取文件内容
+	webhookCache.Lock()
+	webhookCache.hooks = []webhookConfig{{ID: "cached"}}
+	webhookCache.Unlock()
+	hooks, _ = loadWebhooks()
+	if len(hooks) != 1 || hooks[0].ID != "cached" {
+		t.Errorf("unchanged config was reloaded: %v", hooks)
+	}
+
+	// 同样大小的替换也能被发现
+	saveConfig(oneinferConfig{Webhooks: []webhookConfig{{ID: "b", URL: "http://127.0.0.1:1"}}})
+	hooks, _ = loadWebhooks()
+	if len(hooks) != 1 || hooks[0].ID != "b" {
+		t.Errorf("after replace: %v", hooks)
+	}
+
+	saveConfig(oneinferConfig{})
+	if hooks, _ = loadWebhooks(); len(hooks) != 0 {
+		t.Errorf("after remove: %v", hooks)
+	}
+}
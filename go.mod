@@ -2,15 +2,19 @@ module oneinfer
 
 go 1.22
 
+require (
+	github.com/gorilla/mux v1.8.1
+	github.com/spf13/cobra v1.8.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
 require (
 	github.com/DataDog/go-python3 v0.0.0-20211102160307-40adc605f1fe // indirect
-	github.com/gorilla/mux v1.8.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/sbinet/pygo v0.0.0-20160911133915-785145f2c6e1 // indirect
 	github.com/schollz/progressbar/v3 v3.18.0 // indirect
-	github.com/spf13/cobra v1.8.1 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/term v0.28.0 // indirect
@@ -1,15 +1,14 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"oneinfer/cmd"
 )
 
 func main() {
+	// 错误信息已由 cmd.Execute 输出，这里只负责返回对应的退出码
 	if err := cmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }